@@ -0,0 +1,127 @@
+// Exports and imports the documents of the database.
+//
+//	library export [-o file]
+//	library import <archive.zip|directory>
+//
+// Connects to the database configured by the DB_* environment variables.
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"samuellando.com/internal/db"
+	"samuellando.com/internal/store/document"
+)
+
+var (
+	DB_HOST     = os.Getenv("DB_HOST")
+	DB_PORT     = os.Getenv("DB_PORT")
+	DB_USER     = os.Getenv("DB_USER")
+	DB_PASSWORD = os.Getenv("DB_PASSWORD")
+	DB_NAME     = os.Getenv("DB_NAME")
+)
+
+const USAGE = `usage: library <command> [flags] [args...]
+
+commands:
+  export  writes a zip of every document and the assets they reference
+  import  imports the documents of a zip, or a directory of markdown files
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, USAGE)
+		os.Exit(2)
+	}
+	var ok bool
+	switch os.Args[1] {
+	case "export":
+		ok = exportCommand(os.Args[2:])
+	case "import":
+		ok = importCommand(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, USAGE)
+		os.Exit(2)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func connect() document.Store {
+	con := db.ConnectPostgres(DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME, func(o *db.Options) {
+		o.RetrySecs = -1
+	})
+	return document.CreateStore(con)
+}
+
+func exportCommand(args []string) bool {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("o", "", "write the archive to the file instead of stdout")
+	flags.Parse(args)
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := connect().Export(w); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	return true
+}
+
+// Prints what happened to each file, nothing is imported if any file fails.
+func importCommand(args []string) bool {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, USAGE)
+		return false
+	}
+	archive, closeArchive, err := openArchive(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	defer closeArchive()
+	results, err := connect().Import(archive)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	for _, result := range results {
+		fmt.Println(result)
+	}
+	return true
+}
+
+// Opens the directory, or the zip file.
+func openArchive(name string) (fs.FS, func() error, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(name), func() error { return nil }, nil
+	}
+	r, err := zip.OpenReader(name)
+	if errors.Is(err, zip.ErrFormat) {
+		return nil, nil, fmt.Errorf("%s is not a zip or a directory", name)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, r.Close, nil
+}
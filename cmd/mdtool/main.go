@@ -0,0 +1,135 @@
+// Formats and lints markdown documents.
+//
+//	mdtool fmt [-w] [-l] [files...]
+//	mdtool lint [files...]
+//
+// Without files, the document is read from stdin.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"samuellando.com/internal/markdown"
+)
+
+const USAGE = `usage: mdtool <command> [flags] [files...]
+
+commands:
+  fmt   formats the documents into canonical markdown
+  lint  reports style issues in the documents
+
+Without files, the document is read from stdin.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, USAGE)
+		os.Exit(2)
+	}
+	var ok bool
+	switch os.Args[1] {
+	case "fmt":
+		ok = formatCommand(os.Args[2:])
+	case "lint":
+		ok = lintCommand(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, USAGE)
+		os.Exit(2)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func formatCommand(args []string) bool {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	list := flags.Bool("l", false, "list the files that are not formatted")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		formatted, err := formatReader(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "<stdin>: %s\n", err)
+			return false
+		}
+		fmt.Print(formatted)
+		return true
+	}
+	ok := true
+	for _, name := range flags.Args() {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ok = false
+			continue
+		}
+		formatted, err := markdown.Format(string(content))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			ok = false
+			continue
+		}
+		changed := formatted != string(content)
+		if *list && changed {
+			fmt.Println(name)
+		}
+		if *write && changed {
+			if err := os.WriteFile(name, []byte(formatted), 0644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				ok = false
+			}
+		}
+		if !*write && !*list {
+			fmt.Print(formatted)
+		}
+	}
+	return ok
+}
+
+func formatReader(r io.Reader) (string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return markdown.Format(string(content))
+}
+
+// Prints the issues of each document, fails if there are any.
+func lintCommand(args []string) bool {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		return lint("<stdin>", string(content))
+	}
+	ok := true
+	for _, name := range flags.Args() {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ok = false
+			continue
+		}
+		ok = lint(name, string(content)) && ok
+	}
+	return ok
+}
+
+func lint(name, content string) bool {
+	issues, err := markdown.Lint(content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		return false
+	}
+	for _, issue := range issues {
+		fmt.Printf("%s:%s\n", name, issue)
+	}
+	return len(issues) == 0
+}
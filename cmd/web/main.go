@@ -1,22 +1,31 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	htmlTemplate "html/template"
 	"samuellando.com/internal/auth"
+	"samuellando.com/internal/cache"
 	"samuellando.com/internal/db"
+	"samuellando.com/internal/markdown"
+	"samuellando.com/internal/markdown/latex"
 	"samuellando.com/internal/middleware"
+	"samuellando.com/internal/related"
 	"samuellando.com/internal/search"
 	"samuellando.com/internal/store/asset"
 	"samuellando.com/internal/store/document"
 	"samuellando.com/internal/store/project"
 	"samuellando.com/internal/store/tag"
+	"samuellando.com/internal/store/trash"
 	"samuellando.com/internal/template"
 )
 
@@ -30,6 +39,8 @@ var (
 	DB_USER     = os.Getenv("DB_USER")
 	DB_PASSWORD = os.Getenv("DB_PASSWORD")
 	DB_NAME     = os.Getenv("DB_NAME")
+	// Deleted items are purged from the trash after this many days.
+	TRASH_RETENTION_DAYS = os.Getenv("TRASH_RETENTION_DAYS")
 )
 
 var TEMPLATE_FUNCTIONS = template.FuncMap{
@@ -60,6 +71,17 @@ var TEMPLATE_FUNCTIONS = template.FuncMap{
 	"includes": func(s string, arr []string) bool {
 		return slices.Contains(arr, s)
 	},
+	// The query of the request with the offset of another page, ie
+	// "?filter-tag=go&offset=20".
+	"pageQuery": func(req *http.Request, offset int) string {
+		query := req.URL.Query()
+		if offset > 0 {
+			query.Set("offset", strconv.Itoa(offset))
+		} else {
+			query.Del("offset")
+		}
+		return "?" + query.Encode()
+	},
 }
 
 func main() {
@@ -71,17 +93,65 @@ func main() {
 		})
 	defer db.Close()
 
+	markdown.RenderMath = latex.Renderer(db)
+	document.HtmlCache = cache.NewLRU(256, func(co *cache.CacheOptions) {
+		co.MaxAge = 7 * 24 * time.Hour
+		co.Db = db
+	})
+
 	documentStore := document.CreateStore(db)
 	projectStore := project.CreateStore(db)
 	assetStore := asset.CreateStore(db)
 	tagStore := tag.CreateStore(db)
 
+	trashBin := trash.Trash{
+		Documents: documentStore,
+		Assets:    assetStore,
+		Tags:      tagStore,
+	}
+
+	// Documents saved before their links were tracked have none.
+	if err := documentStore.RebuildLinks(); err != nil {
+		log.Println("Failed to rebuild the document links:", err)
+	}
+	go documentStore.RunPublisher(context.Background(), time.Minute)
+	go trashBin.RunRetention(context.Background(), trashRetention(), time.Hour)
+
+	markdown.ResolveImage = assetStore.ResolveImage
+	related.Sources = []func() ([]related.Item, error){
+		documentStore.RelatedItems,
+		projectStore.RelatedItems,
+	}
+	registerShortcodes(templates, documentStore, projectStore, assetStore)
+
 	th := template.Handler{
 		Templates: *templates,
 		// At this point, we throw away type safety for convinience on the frontend.
 		ContextValues: template.ContextValues{
-			"DocumentStore": func(ctx template.Context) any { return documentStore },
-			"ProjectStore":  func(ctx template.Context) any { return projectStore },
+			// Anonymous requests only see published documents.
+			"DocumentStore": func(ctx template.Context) any {
+				visible, err := documentStore.Visible(ctx.Get("Admin").(bool))
+				if err != nil {
+					return nil
+				}
+				return visible
+			},
+			// The page of documents selected by the query parameters, see
+			// document.ParseListOptions.
+			"DocumentPage": func(ctx template.Context) any {
+				req := ctx.Get("Req").(*http.Request)
+				opts := document.ParseListOptions(req.URL.Query())
+				opts.Published = !ctx.Get("Admin").(bool)
+				page, err := documentStore.List(opts)
+				if err != nil {
+					log.Println("Failed to list the documents:", err)
+					return nil
+				}
+				return page
+			},
+			"Statuses":     func(ctx template.Context) any { return document.Statuses },
+			"Sorts":        func(ctx template.Context) any { return document.Sorts },
+			"ProjectStore": func(ctx template.Context) any { return projectStore },
 			"ProjectGroups": func(ctx template.Context) any {
 				filterTags := ctx.Get("FilterTags").([]string)
 				filtered, err := projectStore.Filter(func(p project.Project) bool {
@@ -116,27 +186,67 @@ func main() {
 			},
 			"AssetStore": func(ctx template.Context) any { return assetStore },
 			"TagStore":   func(ctx template.Context) any { return tagStore },
+			"Trash": func(ctx template.Context) any {
+				items, err := trashBin.Items()
+				if err != nil {
+					return nil
+				}
+				return items
+			},
 			"Admin": func(ctx template.Context) any {
 				return auth.IsAuthenticated(ctx.Get("Req").(*http.Request))
 			},
 			"Reference": func(ctx template.Context) any {
+				// The last id in the path, ie 1 in "/documents/1/revisions".
 				path := ctx.Get("Page").(string)
 				parts := strings.Split(path, "/")
-				ref := parts[len(parts)-1]
-				id, err := strconv.Atoi(ref)
-				if err != nil {
-					return -1
+				for i := len(parts) - 1; i >= 0; i-- {
+					if id, err := strconv.Atoi(parts[i]); err == nil {
+						return id
+					}
 				}
-				return id
+				return -1
 			},
 			"Document": func(ctx template.Context) any {
-				id := ctx.Get("Reference").(int)
-				doc, err := documentStore.GetById(int64(id))
+				ref := ctx.Get("Slug").(string)
+				var doc document.Document
+				var err error
+				if ctx.Get("Admin").(bool) {
+					doc, err = documentStore.GetByReference(ref)
+				} else {
+					preview := ctx.Get("Req").(*http.Request).FormValue("preview")
+					doc, err = documentStore.GetPublishedByReference(ref, preview)
+				}
 				if err != nil {
 					return nil
 				}
 				return doc
 			},
+			"AllSeries": func(ctx template.Context) any {
+				series, err := documentStore.AllSeries()
+				if err != nil {
+					return nil
+				}
+				return series
+			},
+			"Series": func(ctx template.Context) any {
+				id, err := strconv.ParseInt(ctx.Get("Slug").(string), 10, 64)
+				if err != nil {
+					return nil
+				}
+				series, err := documentStore.GetSeries(id)
+				if err != nil {
+					return nil
+				}
+				return series
+			},
+			"RevisionDiff": func(ctx template.Context) any {
+				doc, ok := ctx.Get("Document").(document.Document)
+				if !ok {
+					return nil
+				}
+				return revisionDiff(ctx.Get("Req").(*http.Request), doc)
+			},
 			"Project": func(ctx template.Context) any {
 				id := ctx.Get("Reference").(int)
 				proj, err := projectStore.GetById(int64(id))
@@ -158,6 +268,7 @@ func main() {
 				}
 			},
 		},
+		Redirect: documentRedirect,
 	}
 
 	ah := asset.Handler{
@@ -166,17 +277,29 @@ func main() {
 	tagh := tag.Handler{
 		Store: tagStore,
 	}
+	trashh := trash.Handler{
+		Trash: trashBin,
+	}
 
 	docTemplate := templates.Lookup("document")
 	if docTemplate == nil {
 		panic("Must define document template")
 	}
+	lintTemplate := templates.Lookup("lint")
+	if lintTemplate == nil {
+		panic("Must define lint template")
+	}
 	dh := document.Handler{
 		Template:      *docTemplate,
+		LintTemplate:  *lintTemplate,
 		DocumentStore: documentStore,
 		TagStore:      tagStore,
 	}
 
+	seriesh := document.SeriesHandler{
+		DocumentStore: documentStore,
+	}
+
 	projTemplate := templates.Lookup("project")
 	if projTemplate == nil {
 		panic("Must define project template")
@@ -208,18 +331,165 @@ func main() {
 	http.Handle("POST /asset", middleware.Logging(middleware.Authenticated(&ah)))
 	http.Handle("DELETE /asset/{asset}", middleware.Logging(middleware.Authenticated(&ah)))
 	// Document actions
+	http.Handle("GET /document/{document}", middleware.Logging(middleware.Authenticated(&dh)))
 	http.Handle("POST /document", middleware.Logging(middleware.Authenticated(&dh)))
 	http.Handle("PUT /document/{document}", middleware.Logging(middleware.Authenticated(&dh)))
 	http.Handle("DELETE /document/{document}", middleware.Logging(middleware.Authenticated(&dh)))
+	http.Handle("GET /documents/export", middleware.Logging(middleware.Authenticated(http.HandlerFunc(dh.ExportDocuments))))
+	http.Handle("POST /documents/import", middleware.Logging(middleware.Authenticated(http.HandlerFunc(dh.ImportDocuments))))
+	// Series actions
+	http.Handle("POST /series", middleware.Logging(middleware.Authenticated(&seriesh)))
+	http.Handle("PUT /series/{series}", middleware.Logging(middleware.Authenticated(&seriesh)))
+	http.Handle("DELETE /series/{series}", middleware.Logging(middleware.Authenticated(&seriesh)))
 	// Project actions
+	http.Handle("GET /project/{project}", middleware.Logging(middleware.Authenticated(&ph)))
 	http.Handle("PUT /project/{project}", middleware.Logging(middleware.Authenticated(&ph)))
 	// Handling tag edits
 	http.Handle("PATCH /tag/{tag}", middleware.Logging(middleware.Authenticated(&tagh)))
 	http.Handle("DELETE /tag/{tag}", middleware.Logging(middleware.Authenticated(&tagh)))
+	// Restoring and purging deleted items
+	http.Handle("POST /trash/{kind}/{id}", middleware.Logging(middleware.Authenticated(&trashh)))
+	http.Handle("DELETE /trash/{kind}/{id}", middleware.Logging(middleware.Authenticated(&trashh)))
 
 	http.ListenAndServe(":8080", nil)
 }
 
+// Returns how long deleted items are kept in the trash, 30 days unless
+// TRASH_RETENTION_DAYS is set.
+func trashRetention() time.Duration {
+	days, err := strconv.Atoi(TRASH_RETENTION_DAYS)
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Registers the shortcodes that can be embedded in documents.
+func registerShortcodes(templates *template.Template, documentStore document.Store, projectStore project.Store, assetStore asset.Store) {
+	execute := func(name string, data any) (htmlTemplate.HTML, error) {
+		s := new(strings.Builder)
+		err := templates.ExecuteTemplate(s, name, data)
+		return htmlTemplate.HTML(s.String()), err
+	}
+	idArg := func(args map[string]string) (int64, error) {
+		id, err := strconv.ParseInt(args["id"], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid id '%s'", args["id"])
+		}
+		return id, nil
+	}
+	// {{< project id=123 >}}
+	markdown.RegisterShortcode("project", func(args map[string]string) (htmlTemplate.HTML, error) {
+		id, err := idArg(args)
+		if err != nil {
+			return "", err
+		}
+		proj, err := projectStore.GetById(id)
+		if err != nil {
+			return "", err
+		}
+		if proj.Hidden() {
+			return "", nil
+		}
+		return execute("project", proj)
+	})
+	// {{< asset name="x.png" text="Download" >}}
+	markdown.RegisterShortcode("asset", func(args map[string]string) (htmlTemplate.HTML, error) {
+		a, err := assetStore.GetByName(args["name"])
+		if err != nil {
+			return "", fmt.Errorf("Asset '%s' not found", args["name"])
+		}
+		text := args["text"]
+		if text == "" {
+			text = a.Name()
+		}
+		return execute("asset-link", map[string]string{"Name": a.Name(), "Text": text})
+	})
+	// {{< document id=4 >}}
+	markdown.RegisterShortcode("document", func(args map[string]string) (htmlTemplate.HTML, error) {
+		id, err := idArg(args)
+		if err != nil {
+			return "", err
+		}
+		doc, err := documentStore.GetById(id)
+		if err != nil {
+			return "", err
+		}
+		if !doc.Published() {
+			return "", nil
+		}
+		return execute("document-card", doc)
+	})
+}
+
+// Returns the url of the document page with the document's current slug, if
+// it was requested by one of its old slugs.
+func documentRedirect(ctx template.Context) string {
+	slug := ctx.Get("Slug").(string)
+	page := ctx.Get("Page").(string)
+	if slug == "" || !strings.Contains(page, "/documents/") {
+		return ""
+	}
+	doc, ok := ctx.Get("Document").(document.Document)
+	if !ok || doc.Slug() == slug {
+		return ""
+	}
+	// Ids are not redirected, the admin pages link to them.
+	if _, err := strconv.Atoi(slug); err == nil {
+		return ""
+	}
+	parts := strings.Split(page, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] == slug {
+			parts[i] = url.PathEscape(doc.Slug())
+			break
+		}
+	}
+	target := strings.Join(parts, "/")
+	if query := ctx.Get("Req").(*http.Request).URL.RawQuery; query != "" {
+		target += "?" + query
+	}
+	return target
+}
+
+// Returns the diff between the "from" and "to" revisions of the request, by
+// default between the latest revision and the one before it. Returns nil if
+// there is nothing to compare.
+func revisionDiff(req *http.Request, doc document.Document) map[string]any {
+	revisions, err := doc.Revisions()
+	if err != nil || len(revisions) == 0 {
+		return nil
+	}
+	find := func(field string, fallback int) (document.Revision, bool) {
+		id, err := strconv.ParseInt(req.FormValue(field), 10, 64)
+		if err != nil {
+			if fallback < len(revisions) {
+				return revisions[fallback], true
+			}
+			return document.Revision{}, false
+		}
+		for _, r := range revisions {
+			if r.Id() == id {
+				return r, true
+			}
+		}
+		return document.Revision{}, false
+	}
+	from, ok := find("from", 1)
+	if !ok {
+		return nil
+	}
+	to, ok := find("to", 0)
+	if !ok {
+		return nil
+	}
+	return map[string]any{
+		"From":  from,
+		"To":    to,
+		"Lines": to.Diff(from),
+	}
+}
+
 func createSearchHandler(template *htmlTemplate.Template, projectStore project.Store) http.HandlerFunc {
 	searchStore, err := projectStore.Filter(func(p project.Project) bool {
 		return !p.Hidden()
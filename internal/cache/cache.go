@@ -7,6 +7,7 @@ import (
 	"log"
 	"reflect"
 	"runtime"
+	"sync"
 	"time"
 )
 
@@ -25,14 +26,33 @@ type cacheElement struct {
 	value   []byte
 }
 
-var localCache = make(map[string]cacheElement)
+var (
+	localCache = make(map[string]cacheElement)
+	// Guards localCache, the cached functions are called concurrently.
+	localMu sync.Mutex
+)
 
 func resetCache() {
+	localMu.Lock()
+	defer localMu.Unlock()
 	for k := range localCache {
 		delete(localCache, k)
 	}
 }
 
+func localGet(key string) (cacheElement, bool) {
+	localMu.Lock()
+	defer localMu.Unlock()
+	elem, ok := localCache[key]
+	return elem, ok
+}
+
+func localSet(key string, elem cacheElement) {
+	localMu.Lock()
+	defer localMu.Unlock()
+	localCache[key] = elem
+}
+
 // Function that caches the result of the provided function f.
 // It uses in-memory and optional external database caching.
 // The cache key is derived from the function's name.
@@ -56,7 +76,7 @@ func Cached(f func() ([]byte, error), opts ...func(*CacheOptions)) func() ([]byt
 	return func() ([]byte, error) {
 		log.Println("Checking cache for function:", funcDetails.Name(), "with key:", cacheKey)
 
-		if cachedElem, exists := localCache[cacheKey]; exists && time.Until(cachedElem.validTo) > 0 {
+		if cachedElem, exists := localGet(cacheKey); exists && time.Until(cachedElem.validTo) > 0 {
 			log.Println("Cache hit, valid for:", time.Until(cachedElem.validTo))
 			return cachedElem.value, nil
 		}
@@ -64,7 +84,7 @@ func Cached(f func() ([]byte, error), opts ...func(*CacheOptions)) func() ([]byt
 		// Cache miss, check the external cache.
 		cachedElem, err := dbCacheGet(cacheKey, cacheOptions)
 		if err == nil {
-			localCache[cacheKey] = cachedElem
+			localSet(cacheKey, cachedElem)
 			return cachedElem.value, nil
 		}
 
@@ -76,7 +96,7 @@ func Cached(f func() ([]byte, error), opts ...func(*CacheOptions)) func() ([]byt
 
 		newElem := cacheElement{validTo: time.Now().Add(cacheOptions.MaxAge), value: data}
 		dbCacheUpdate(cacheKey, newElem, cacheOptions)
-		localCache[cacheKey] = newElem
+		localSet(cacheKey, newElem)
 
 		return data, nil
 	}
@@ -106,7 +126,7 @@ func ParamCached(f func() ([]byte, error), paramKey string, opts ...func(*CacheO
 	return func() ([]byte, error) {
 		log.Println("Checking cache for function:", funcDetails.Name(), "with key:", cacheKey)
 
-		if cachedElem, exists := localCache[cacheKey]; exists && time.Until(cachedElem.validTo) > 0 {
+		if cachedElem, exists := localGet(cacheKey); exists && time.Until(cachedElem.validTo) > 0 {
 			log.Println("Cache hit, valid for:", time.Until(cachedElem.validTo))
 			return cachedElem.value, nil
 		}
@@ -114,7 +134,7 @@ func ParamCached(f func() ([]byte, error), paramKey string, opts ...func(*CacheO
 		// Cache miss, check the external cache.
 		cachedElem, err := dbCacheGet(cacheKey, cacheOptions)
 		if err == nil {
-			localCache[cacheKey] = cachedElem
+			localSet(cacheKey, cachedElem)
 			return cachedElem.value, nil
 		}
 
@@ -126,7 +146,7 @@ func ParamCached(f func() ([]byte, error), paramKey string, opts ...func(*CacheO
 
 		newElem := cacheElement{validTo: time.Now().Add(cacheOptions.MaxAge), value: data}
 		dbCacheUpdate(cacheKey, newElem, cacheOptions)
-		localCache[cacheKey] = newElem
+		localSet(cacheKey, newElem)
 
 		return data, nil
 	}
@@ -46,3 +46,15 @@ func dbCacheUpdate(key string, elem cacheElement, options CacheOptions) {
 		}
 	}
 }
+
+// Remove a cache entry from the external database.
+func dbCacheDelete(key string, options CacheOptions) {
+	if options.Db != nil {
+		ctx := context.TODO()
+		queries := data.New(options.Db)
+		err := queries.DeleteCacheByKey(ctx, key)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// An in-memory cache holding at most size entries, the least recently used
+// entries are evicted first. Entries are also stored in the external cache if
+// a Db is provided.
+//
+// Unlike Cached, the keys are provided by the caller, it is safe for
+// concurrent use.
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	options CacheOptions
+	// The most recently used entries are at the front.
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	elem cacheElement
+}
+
+func NewLRU(size int, opts ...func(*CacheOptions)) *LRU {
+	cacheOptions := CacheOptions{MaxAge: time.Hour}
+	for _, opt := range opts {
+		opt(&cacheOptions)
+	}
+	return &LRU{
+		size:    size,
+		options: cacheOptions,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Returns the cached value for the key, or caches the result of f.
+func (c *LRU) Get(key string, f func() ([]byte, error)) ([]byte, error) {
+	if value, ok := c.get(key); ok {
+		return value, nil
+	}
+	// Cache miss, check the external cache.
+	elem, err := dbCacheGet(key, c.options)
+	if err == nil {
+		c.set(key, elem)
+		return elem.value, nil
+	}
+	data, err := f()
+	if err != nil {
+		return nil, err
+	}
+	elem = cacheElement{validTo: time.Now().Add(c.options.MaxAge), value: data}
+	dbCacheUpdate(key, elem, c.options)
+	c.set(key, elem)
+	return data, nil
+}
+
+// Removes the entry from the cache, and from the external cache.
+func (c *LRU) Remove(key string) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+	dbCacheDelete(key, c.options)
+}
+
+// The number of entries in memory.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruEntry)
+	if time.Until(entry.elem.validTo) <= 0 {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return entry.elem.value, true
+}
+
+func (c *LRU) set(key string, elem cacheElement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size <= 0 {
+		return
+	}
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruEntry).elem = elem
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, elem: elem})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
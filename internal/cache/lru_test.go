@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func counter(calls *int, value string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		*calls++
+		return []byte(value), nil
+	}
+}
+
+func TestLRU_Hit(t *testing.T) {
+	c := NewLRU(2)
+	calls := 0
+	for i := 0; i < 2; i++ {
+		data, err := c.Get("a", counter(&calls, "value"))
+		if err != nil || string(data) != "value" {
+			t.Fatalf("Expected 'value', got %s, error: %v", data, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected the second call to be a hit, got %d calls", calls)
+	}
+}
+
+func TestLRU_Eviction(t *testing.T) {
+	c := NewLRU(2)
+	calls := 0
+	c.Get("a", counter(&calls, "a"))
+	c.Get("b", counter(&calls, "b"))
+	// a is now the most recently used, so b is evicted.
+	c.Get("a", counter(&calls, "a"))
+	c.Get("c", counter(&calls, "c"))
+	if c.Len() != 2 || calls != 3 {
+		t.Fatalf("Expected 2 entries after 3 calls, got %d entries after %d calls", c.Len(), calls)
+	}
+	c.Get("a", counter(&calls, "a"))
+	if calls != 3 {
+		t.Fatal("Expected a to still be cached")
+	}
+	c.Get("b", counter(&calls, "b"))
+	if calls != 4 {
+		t.Fatal("Expected b to have been evicted")
+	}
+}
+
+func TestLRU_RemoveAndExpiry(t *testing.T) {
+	c := NewLRU(2, func(o *CacheOptions) {
+		o.MaxAge = time.Millisecond
+	})
+	calls := 0
+	c.Get("a", counter(&calls, "a"))
+	c.Remove("a")
+	c.Get("a", counter(&calls, "a"))
+	if calls != 2 {
+		t.Fatalf("Expected a miss after removing, got %d calls", calls)
+	}
+	time.Sleep(2 * time.Millisecond)
+	c.Get("a", counter(&calls, "a"))
+	if calls != 3 {
+		t.Fatalf("Expected a miss after expiry, got %d calls", calls)
+	}
+}
+
+func TestLRU_Error(t *testing.T) {
+	c := NewLRU(2)
+	_, err := c.Get("a", fetchDataWithError)
+	if err == nil || c.Len() != 0 {
+		t.Fatalf("Expected the error not to be cached, got %v", err)
+	}
+}
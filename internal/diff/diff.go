@@ -0,0 +1,104 @@
+// This package provides a line level diff between two texts.
+package diff
+
+import (
+	"strings"
+)
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// A line of the diff.
+type Line struct {
+	Op   Op
+	Text string
+	// The line numbers in the old and new text, starting at 1, or 0 if the
+	// line is not in that text.
+	Old int
+	New int
+}
+
+// Returns the lines of both texts, with the lines removed from the old text
+// before the lines inserted in the new text, using the longest common
+// subsequence of lines.
+func Lines(old, new string) []Line {
+	a, b := split(old), split(new)
+	// The common prefix and suffix are equal, only the middle needs to be
+	// compared.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	lines := make([]Line, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{Op: Equal, Text: a[i], Old: i + 1, New: i + 1})
+	}
+	lines = append(lines, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := suffix; i > 0; i-- {
+		lines = append(lines, Line{Op: Equal, Text: a[len(a)-i], Old: len(a) - i + 1, New: len(b) - i + 1})
+	}
+	return lines
+}
+
+// Reports if the diff has any inserted or deleted lines.
+func Changed(lines []Line) bool {
+	for _, l := range lines {
+		if l.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Diffs the lines, offset by the lines before them.
+func middle(a, b []string, oldOffset, newOffset int) []Line {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	lines := make([]Line, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, Line{Op: Equal, Text: a[i], Old: oldOffset + i + 1, New: newOffset + j + 1})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, Line{Op: Delete, Text: a[i], Old: oldOffset + i + 1})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: b[j], New: newOffset + j + 1})
+			j++
+		}
+	}
+	return lines
+}
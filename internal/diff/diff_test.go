@@ -0,0 +1,75 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+// Renders the diff like a unified diff without headers.
+func format(lines []Line) string {
+	s := new(strings.Builder)
+	for _, l := range lines {
+		switch l.Op {
+		case Equal:
+			s.WriteString(" ")
+		case Insert:
+			s.WriteString("+")
+		case Delete:
+			s.WriteString("-")
+		}
+		s.WriteString(l.Text + "\n")
+	}
+	return s.String()
+}
+
+func TestLines(t *testing.T) {
+	cases := []struct {
+		old, new, expected string
+	}{
+		{"", "", ""},
+		{"a\nb\n", "a\nb\n", " a\n b\n"},
+		{"", "a\nb", "+a\n+b\n"},
+		{"a\nb", "", "-a\n-b\n"},
+		{"a\nb\nc", "a\nc", " a\n-b\n c\n"},
+		{"a\nc", "a\nb\nc", " a\n+b\n c\n"},
+		{"a\nb\nc", "a\nx\nc", " a\n-b\n+x\n c\n"},
+		{"a\nb\nc\nd", "b\nc\nd\ne", "-a\n b\n c\n d\n+e\n"},
+		{"x\na\ny\nb\nz", "a\nq\nb", "-x\n a\n-y\n+q\n b\n-z\n"},
+		{"a\r\nb\r\n", "a\nb", " a\n b\n"},
+	}
+	for _, c := range cases {
+		got := format(Lines(c.old, c.new))
+		if got != c.expected {
+			t.Fatalf("Expected diff of %q and %q to be\n%s\ngot\n%s", c.old, c.new, c.expected, got)
+		}
+	}
+}
+
+func TestLineNumbers(t *testing.T) {
+	lines := Lines("a\nb\nc\nd", "a\nx\nc\nd\ne")
+	expected := []Line{
+		{Op: Equal, Text: "a", Old: 1, New: 1},
+		{Op: Delete, Text: "b", Old: 2},
+		{Op: Insert, Text: "x", New: 2},
+		{Op: Equal, Text: "c", Old: 3, New: 3},
+		{Op: Equal, Text: "d", Old: 4, New: 4},
+		{Op: Insert, Text: "e", New: 5},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d", len(expected), len(lines))
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Fatalf("Expected line %d to be %+v, got %+v", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestChanged(t *testing.T) {
+	if Changed(Lines("a\nb", "a\nb")) {
+		t.Fatal("Expected identical texts to be unchanged")
+	}
+	if !Changed(Lines("a\nb", "a\nc")) {
+		t.Fatal("Expected different texts to be changed")
+	}
+}
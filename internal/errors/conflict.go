@@ -0,0 +1,14 @@
+package errors
+
+// Returned when an update is based on an outdated version of an item.
+type ConflictError struct {
+	typ string
+}
+
+func CreateConflictError(typ string) ConflictError {
+	return ConflictError{typ: typ}
+}
+
+func (c ConflictError) Error() string {
+	return c.typ + " was changed by another update."
+}
@@ -0,0 +1,43 @@
+// This package provides the ETag and If-Match headers used for optimistic
+// concurrency, where the tag of an item is its version number.
+package etag
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Returned by Match when the request has no version to match.
+var ErrMissing = fmt.Errorf("The If-Match header or version field is required")
+
+// Formats the version as an ETag ie "3".
+func Format(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// Sets the ETag header of the response to the version.
+func Set(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", Format(version))
+}
+
+// Reports if the If-Match header of the request, or its "version" form field
+// for html forms, matches the version.
+//
+// Returns ErrMissing if the request has neither.
+func Match(req *http.Request, version int64) (bool, error) {
+	if header := req.Header.Get("If-Match"); header != "" {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || tag == Format(version) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	if field := req.FormValue("version"); field != "" {
+		return field == strconv.FormatInt(version, 10), nil
+	}
+	return false, ErrMissing
+}
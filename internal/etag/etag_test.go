@@ -0,0 +1,55 @@
+package etag
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	if Format(3) != `"3"` {
+		t.Fatalf(`Expected "3", got %s`, Format(3))
+	}
+}
+
+func TestMatchHeader(t *testing.T) {
+	cases := map[string]bool{
+		`"3"`:      true,
+		`"2"`:      false,
+		`"2", "3"`: true,
+		`*`:        true,
+		`W/"3"`:    false,
+		`3`:        false,
+	}
+	for header, expected := range cases {
+		req := httptest.NewRequest("PUT", "/document/1", nil)
+		req.Header.Set("If-Match", header)
+		match, err := Match(req, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match != expected {
+			t.Fatalf("Expected If-Match %s to match version 3: %t", header, expected)
+		}
+	}
+}
+
+func TestMatchField(t *testing.T) {
+	form := url.Values{"version": {"3"}}
+	req := httptest.NewRequest("PUT", "/document/1", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if match, err := Match(req, 3); err != nil || !match {
+		t.Fatalf("Expected the version field to match, got %t (%v)", match, err)
+	}
+	if match, err := Match(req, 4); err != nil || match {
+		t.Fatalf("Expected the version field not to match, got %t (%v)", match, err)
+	}
+}
+
+func TestMatchMissing(t *testing.T) {
+	req := httptest.NewRequest("PUT", "/document/1", nil)
+	if _, err := Match(req, 3); err != ErrMissing {
+		t.Fatalf("Expected ErrMissing, got %v", err)
+	}
+}
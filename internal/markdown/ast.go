@@ -0,0 +1,489 @@
+package markdown
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/samuellando/gositter"
+)
+
+// The kind of markdown element a node represents.
+type Kind string
+
+const (
+	DocumentNode          Kind = "document"
+	HeadingNode           Kind = "heading"
+	ParagraphNode         Kind = "paragraph"
+	BlockquoteNode        Kind = "blockquote"
+	CalloutNode           Kind = "callout"
+	CodeBlockNode         Kind = "codeblock"
+	MathBlockNode         Kind = "mathblock"
+	TableNode             Kind = "table"
+	TableRowNode          Kind = "tablerow"
+	TableCellNode         Kind = "tablecell"
+	ListNode              Kind = "list"
+	ListItemNode          Kind = "listitem"
+	FootnoteNode          Kind = "footnote"
+	TocNode               Kind = "toc"
+	TextNode              Kind = "text"
+	EmphasisNode          Kind = "emphasis"
+	StrongNode            Kind = "strong"
+	StrikethroughNode     Kind = "strikethrough"
+	CodeNode              Kind = "code"
+	MathNode              Kind = "math"
+	LinkNode              Kind = "link"
+	ImageNode             Kind = "image"
+	FootnoteReferenceNode Kind = "footnotereference"
+	ShortcodeNode         Kind = "shortcode"
+)
+
+// A position in the markdown source, lines and columns start at 1.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// A node of the markdown syntax tree.
+//
+// Only the fields relevant to the node's kind are set.
+type Node struct {
+	Kind     Kind
+	Pos      Position
+	Children []*Node
+	// The text of text, code and math nodes, and the source of code blocks.
+	Text string
+	// The level of headings.
+	Level int
+	// The destination and title of links and images, references are resolved.
+	Href  string
+	Title string
+	// The alt text of images.
+	Alt string
+	// The language of code blocks.
+	Lang string
+	// Ordered lists and their first number.
+	Ordered bool
+	Start   int
+	// Task list items.
+	Task    bool
+	Checked bool
+	// The text alignment of table cells.
+	Align string
+	// Set for the rows in the head of a table.
+	Header bool
+	// The label of footnotes and footnote references, the kind of callouts ie
+	// "note", and the name of shortcodes.
+	Label string
+	// The arguments of shortcodes.
+	Args map[string]string
+}
+
+var emphasisKinds = map[string]Kind{"em": EmphasisNode, "strong": StrongNode, "del": StrikethroughNode}
+
+// Words per minute, used for the reading time.
+const readingSpeed = 200
+
+// Parses the markdown into a syntax tree.
+//
+// Errors are returned as a *ParseError.
+func Parse(md string) (*Node, error) {
+	r, err := newRenderer(md)
+	if err != nil {
+		return nil, err
+	}
+	root := &Node{Kind: DocumentNode, Pos: Position{Line: 1, Column: 1}, Children: make([]*Node, 0)}
+	for _, seg := range r.segments {
+		blocks, err := r.blocks(seg.tree)
+		if err != nil {
+			var tags []gositter.SyntaxTree
+			if tags = findAny(seg.tree, "tag"); len(tags) == 0 {
+				return nil, err
+			}
+			return nil, r.blockError(tags[0], err)
+		}
+		root.Children = append(root.Children, blocks...)
+	}
+	return root, nil
+}
+
+func (r *renderer) node(kind Kind, t gositter.SyntaxTree) *Node {
+	line, column := r.position(t)
+	return &Node{
+		Kind: kind,
+		Pos:  Position{Offset: r.offsets[t], Line: line, Column: column},
+	}
+}
+
+func (r *renderer) blocks(t gositter.SyntaxTree) ([]*Node, error) {
+	nodes := make([]*Node, 0)
+	for _, block := range findAny(t, "header", "p", "blockquote", "codeblock", "mathblock",
+		"table", "list", "footnotedef", "toc", "shortcodeblock") {
+		var n *Node
+		var err error
+		switch block.Tag() {
+		case "header":
+			n = r.node(HeadingNode, block)
+			n.Level = int(block.Nodes()[0].Tag()[1] - '0')
+			n.Children, err = r.inlines(block.Find("span")[0])
+		case "p":
+			n = r.node(ParagraphNode, block)
+			n.Children, err = r.inlines(block)
+		case "blockquote":
+			n = r.node(BlockquoteNode, block)
+			if kinds := block.Find("callouttype"); len(kinds) > 0 {
+				n.Kind = CalloutNode
+				n.Label = strings.ToLower(kinds[0].Value())
+			}
+			quote := block.Find("quote")[0]
+			p := r.node(ParagraphNode, quote)
+			p.Children, err = r.inlines(quote)
+			n.Children = []*Node{p}
+		case "codeblock":
+			n = r.node(CodeBlockNode, block)
+			if langs := block.Find("lang"); len(langs) > 0 {
+				n.Lang = strings.ToLower(langs[0].Value())
+			}
+			lines := make([]string, 0)
+			for _, line := range block.Find("codeline") {
+				text := ""
+				if lt := line.Find("linetext"); len(lt) > 0 {
+					text = lt[0].Value()
+				}
+				lines = append(lines, text)
+			}
+			n.Text = strings.Join(lines, "\n")
+		case "mathblock":
+			n = r.node(MathBlockNode, block)
+			n.Text = strings.TrimSpace(block.Nodes()[1].Value())
+		case "table":
+			n, err = r.tableNode(block)
+		case "list":
+			var lists []*list
+			lists, err = parseList(block)
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range lists {
+				ln, err := r.listNode(block, l)
+				if err != nil {
+					return nil, err
+				}
+				nodes = append(nodes, ln)
+			}
+			continue
+		case "footnotedef":
+			n = r.node(FootnoteNode, block)
+			n.Label = block.Find("label")[0].Value()
+			n.Children, err = r.inlines(block.Find("footnotetext")[0])
+		case "toc":
+			n = r.node(TocNode, block)
+		case "shortcodeblock":
+			n, err = r.shortcodeNode(block.Find("shortcode")[0])
+		}
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func (r *renderer) tableNode(t gositter.SyntaxTree) (*Node, error) {
+	n := r.node(TableNode, t)
+	delim := t.Find("tabledelim")[0]
+	aligns := make([]string, 0)
+	for _, a := range append(delim.Find("align"), delim.Find("lastalign")...) {
+		aligns = append(aligns, alignment(a.Value()))
+	}
+	rows := append(t.Find("thead"), t.Find("trow")...)
+	for i, row := range rows {
+		rn := r.node(TableRowNode, row)
+		rn.Header = i == 0
+		for j, c := range append(row.Find("cell"), row.Find("lastcell")...) {
+			cn := r.node(TableCellNode, c)
+			if j < len(aligns) {
+				cn.Align = aligns[j]
+			}
+			var err error
+			cn.Children, err = r.inlines(c)
+			if err != nil {
+				return nil, err
+			}
+			rn.Children = append(rn.Children, cn)
+		}
+		n.Children = append(n.Children, rn)
+	}
+	return n, nil
+}
+
+func (r *renderer) listNode(t gositter.SyntaxTree, l *list) (*Node, error) {
+	n := r.node(ListNode, t)
+	n.Ordered, n.Start = l.Ordered, l.Start
+	for _, item := range l.Items {
+		var in *Node
+		if len(item.paragraphs) > 0 {
+			in = r.node(ListItemNode, item.paragraphs[0][0])
+		} else {
+			in = &Node{Kind: ListItemNode, Pos: n.Pos}
+		}
+		in.Task, in.Checked = item.Task, item.Checked
+		for _, lines := range item.paragraphs {
+			p := r.node(ParagraphNode, lines[0])
+			for i, li := range lines {
+				if i > 0 {
+					p.Children = appendText(p.Children, &Node{Kind: TextNode, Text: "\n"})
+				}
+				inline, err := r.inlines(li)
+				if err != nil {
+					return nil, err
+				}
+				for _, c := range inline {
+					p.Children = appendText(p.Children, c)
+				}
+			}
+			in.Children = append(in.Children, p)
+		}
+		for _, sub := range item.lists {
+			sn, err := r.listNode(t, sub)
+			if err != nil {
+				return nil, err
+			}
+			in.Children = append(in.Children, sn)
+		}
+		n.Children = append(n.Children, in)
+	}
+	return n, nil
+}
+
+// Returns the inline nodes of the tree, adjacent text is merged.
+func (r *renderer) inlines(t gositter.SyntaxTree) ([]*Node, error) {
+	nodes := make([]*Node, 0)
+	for _, child := range t.Nodes() {
+		var n *Node
+		var err error
+		switch child.Tag() {
+		case "em", "strong", "del":
+			n = r.node(emphasisKinds[child.Tag()], child)
+			n.Children, err = r.inlines(child.Nodes()[1])
+		case "code":
+			n = r.node(CodeNode, child)
+			n.Text = child.Nodes()[0].Nodes()[1].Value()
+		case "math":
+			n = r.node(MathNode, child)
+			n.Text = child.Nodes()[1].Value()
+		case "escape":
+			n = r.node(TextNode, child)
+			n.Text = child.Nodes()[1].Value()
+		case "footnoteref":
+			n = r.node(FootnoteReferenceNode, child)
+			n.Label = child.Find("label")[0].Value()
+		case "a":
+			n, err = r.linkNode(child)
+		case "img":
+			n, err = r.imageNode(child)
+		case "shortcode":
+			n, err = r.shortcodeNode(child)
+		case "quotemarker", "html":
+			continue
+		default:
+			if len(child.Nodes()) == 0 {
+				n = r.node(TextNode, child)
+				n.Text = child.Value()
+				break
+			}
+			var inner []*Node
+			inner, err = r.inlines(child)
+			if err != nil {
+				return nil, err
+			}
+			for _, in := range inner {
+				nodes = appendText(nodes, in)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		nodes = appendText(nodes, n)
+	}
+	return nodes, nil
+}
+
+func (r *renderer) shortcodeNode(t gositter.SyntaxTree) (*Node, error) {
+	n := r.node(ShortcodeNode, t)
+	var err error
+	n.Label, n.Args, err = shortcodeArgs(t)
+	return n, err
+}
+
+func (r *renderer) linkNode(t gositter.SyntaxTree) (*Node, error) {
+	n := r.node(LinkNode, t)
+	var text string
+	var err error
+	if imgs := t.Find("img"); len(imgs) > 0 {
+		var img *Node
+		img, err = r.imageNode(imgs[0])
+		n.Children = []*Node{img}
+	} else if lts := t.Find("linktext"); len(lts) > 0 {
+		text = lts[0].Value()
+		n.Children, err = r.inlines(lts[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+	nodes := t.Nodes()
+	last := nodes[len(nodes)-1]
+	if refs := last.Find("ref"); len(refs) > 0 {
+		ld, err := r.resolve(refs[0], text)
+		if err != nil {
+			return nil, err
+		}
+		n.Href, n.Title = ld.Href, ld.Title
+	} else if hrefs := last.Find("href"); len(hrefs) > 0 {
+		n.Href = hrefs[0].Value()
+		if titles := last.Find("title"); len(titles) > 0 {
+			n.Title = unquoteTitle(titles[0].Value())
+		}
+	}
+	return n, nil
+}
+
+func (r *renderer) imageNode(t gositter.SyntaxTree) (*Node, error) {
+	n := r.node(ImageNode, t)
+	n.Alt = t.Find("alt")[0].Value()
+	if refs := t.Find("ref"); len(refs) > 0 {
+		ld, err := r.resolve(refs[0], n.Alt)
+		if err != nil {
+			return nil, err
+		}
+		n.Href, n.Title = ld.Href, ld.Title
+	} else if hrefs := t.Find("href"); len(hrefs) > 0 {
+		n.Href = hrefs[0].Value()
+		if titles := t.Find("title"); len(titles) > 0 {
+			n.Title = unquoteTitle(titles[0].Value())
+		}
+	}
+	return n, nil
+}
+
+// Appends the node, merging it into the previous node if both are text.
+func appendText(nodes []*Node, n *Node) []*Node {
+	if n.Kind == TextNode && len(nodes) > 0 && nodes[len(nodes)-1].Kind == TextNode {
+		nodes[len(nodes)-1].Text += n.Text
+		return nodes
+	}
+	return append(nodes, n)
+}
+
+// Calls f for the node and each of its descendants, depth first. Children are
+// skipped when f returns false.
+func (n *Node) Walk(f func(*Node) bool) {
+	if !f(n) {
+		return
+	}
+	for _, c := range n.Children {
+		c.Walk(f)
+	}
+}
+
+func (n *Node) findAll(kind Kind) []*Node {
+	found := make([]*Node, 0)
+	n.Walk(func(c *Node) bool {
+		if c.Kind == kind {
+			found = append(found, c)
+		}
+		return true
+	})
+	return found
+}
+
+// All the images, including images inside links.
+func (n *Node) Images() []*Node {
+	return n.findAll(ImageNode)
+}
+
+// All the links.
+func (n *Node) Links() []*Node {
+	return n.findAll(LinkNode)
+}
+
+func (n *Node) isBlock() bool {
+	switch n.Kind {
+	case DocumentNode, HeadingNode, ParagraphNode, BlockquoteNode, CalloutNode, CodeBlockNode, MathBlockNode,
+		TableNode, TableRowNode, ListNode, ListItemNode, FootnoteNode, TocNode:
+		return true
+	}
+	return false
+}
+
+// Returns the text without any markup, each block on its own line.
+func (n *Node) PlainText() string {
+	lines := make([]string, 0)
+	line := new(strings.Builder)
+	flush := func() {
+		if text := strings.Join(strings.Fields(line.String()), " "); text != "" {
+			lines = append(lines, text)
+		}
+		line.Reset()
+	}
+	var write func(n *Node)
+	write = func(n *Node) {
+		if n.isBlock() {
+			flush()
+		}
+		switch n.Kind {
+		case TextNode, CodeNode, MathNode, MathBlockNode:
+			line.WriteString(n.Text)
+		case CodeBlockNode:
+			for _, l := range strings.Split(n.Text, "\n") {
+				line.WriteString(l)
+				flush()
+			}
+		case ImageNode:
+			line.WriteString(n.Alt)
+		case TableCellNode:
+			line.WriteString(" ")
+		}
+		for _, c := range n.Children {
+			write(c)
+		}
+		if n.isBlock() {
+			flush()
+		}
+	}
+	write(n)
+	return strings.Join(lines, "\n")
+}
+
+// Returns the first words of the prose, skipping headings, code, tables
+// and footnotes. An ellipsis is added if the text is cut.
+func (n *Node) Excerpt(words int) string {
+	prose := make([]string, 0)
+	n.Walk(func(c *Node) bool {
+		switch c.Kind {
+		case HeadingNode, CodeBlockNode, MathBlockNode, TableNode, FootnoteNode, TocNode:
+			return false
+		case ParagraphNode:
+			prose = append(prose, strings.Fields(c.PlainText())...)
+			return false
+		}
+		return true
+	})
+	if len(prose) <= words {
+		return strings.Join(prose, " ")
+	}
+	return strings.Join(prose[:words], " ") + "…"
+}
+
+// The number of words in the text of the document.
+func (n *Node) WordCount() int {
+	return len(strings.Fields(n.PlainText()))
+}
+
+// The time to read the document, rounded up to the minute.
+func (n *Node) ReadingTime() time.Duration {
+	minutes := math.Ceil(float64(n.WordCount()) / readingSpeed)
+	return time.Duration(max(minutes, 1)) * time.Minute
+}
@@ -0,0 +1,109 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := Parse("# Title\n\nSome *text* and a [link][ref].\n\n- one\n  - two\n\n[ref]: https://example.com\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Kind != DocumentNode || len(doc.Children) != 3 {
+		t.Fatalf("Expected a document with 3 blocks, got %+v", doc.Children)
+	}
+	h := doc.Children[0]
+	if h.Kind != HeadingNode || h.Level != 1 || h.Children[0].Text != "Title" {
+		t.Fatalf("Expected a heading, got %+v", h)
+	}
+	p := doc.Children[1]
+	if p.Kind != ParagraphNode || p.Pos.Line != 3 || p.Pos.Column != 1 {
+		t.Fatalf("Expected a paragraph on line 3, got %+v", p)
+	}
+	if p.Children[1].Kind != EmphasisNode || p.Children[1].Children[0].Text != "text" {
+		t.Fatalf("Expected emphasis, got %+v", p.Children[1])
+	}
+	l := doc.Children[2]
+	if l.Kind != ListNode || len(l.Children) != 1 || l.Children[0].Children[1].Kind != ListNode {
+		t.Fatalf("Expected a nested list, got %+v", l)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	doc, err := Parse("## Hello **world**\n\nA ![cat](/cat.png) and `code`.\n\n```go\nx := 1\n```\n\n| a | b |\n|---|---|\n| 1 | 2 |\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Hello world\nA cat and code.\nx := 1\na b\n1 2"
+	if text := doc.PlainText(); text != expected {
+		t.Fatalf("Expected %q, got %q", expected, text)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	doc, err := Parse("# Heading\n\nOne two three.\n\n```\ncode\n```\n\nFour five six.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if excerpt := doc.Excerpt(4); excerpt != "One two three. Four…" {
+		t.Fatalf("Expected a cut excerpt, got %q", excerpt)
+	}
+	if excerpt := doc.Excerpt(10); excerpt != "One two three. Four five six." {
+		t.Fatalf("Expected the whole text, got %q", excerpt)
+	}
+}
+
+func TestImagesAndLinks(t *testing.T) {
+	doc, err := Parse("[![logo](/logo.png)](/home) and [docs](https://example.com)\n\n- ![inline](/a.png)\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	images := doc.Images()
+	if len(images) != 2 || images[0].Href != "/logo.png" || images[1].Alt != "inline" {
+		t.Fatalf("Expected 2 images, got %+v", images)
+	}
+	links := doc.Links()
+	if len(links) != 2 || links[0].Href != "/home" || links[1].Href != "https://example.com" {
+		t.Fatalf("Expected 2 links, got %+v", links)
+	}
+}
+
+func TestWordCount(t *testing.T) {
+	doc, err := Parse("# One two\n\n" + strings.Repeat("word ", 398))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.WordCount() != 400 {
+		t.Fatalf("Expected 400 words, got %d", doc.WordCount())
+	}
+	if doc.ReadingTime() != 2*time.Minute {
+		t.Fatalf("Expected 2 minutes, got %s", doc.ReadingTime())
+	}
+	doc, _ = Parse("Short")
+	if doc.ReadingTime() != time.Minute {
+		t.Fatalf("Expected at least a minute, got %s", doc.ReadingTime())
+	}
+}
+
+func TestParseUndefinedReference(t *testing.T) {
+	if _, err := Parse("[a][missing]"); err == nil {
+		t.Fatal("Expected an error for an undefined reference")
+	}
+}
+
+func TestParseCalloutAndShortcode(t *testing.T) {
+	doc, err := Parse("> [!NOTE]\n> Read this\n\n{{< project id=4 >}}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := doc.Children[0]
+	if c.Kind != CalloutNode || c.Label != "note" || c.Children[0].Children[0].Text != "Read this" {
+		t.Fatalf("Expected a note callout, got %+v", c)
+	}
+	s := doc.Children[1]
+	if s.Kind != ShortcodeNode || s.Label != "project" || s.Args["id"] != "4" || s.Pos.Line != 4 {
+		t.Fatalf("Expected a project shortcode on line 4, got %+v", s)
+	}
+}
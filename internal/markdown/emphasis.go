@@ -0,0 +1,243 @@
+package markdown
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/samuellando/gositter"
+)
+
+// The rules whose items are inline markup, emphasis does not span them. The
+// rules that are only a reference to another take its place in the tree.
+var inlineContainers = []string{"inner", "li", "footnotetext", "cellinner", "lastcell"}
+
+// The rules that only group the items of an inline container.
+var inlineGroups = []string{"", "inline", "textchar"}
+
+var emphasisTags = map[string]string{"*": "em", "**": "strong", "~~": "del"}
+
+// A node of a tree built after parsing.
+type tree struct {
+	tag   string
+	nodes []gositter.SyntaxTree
+}
+
+func (t *tree) Tree() string {
+	s := "(" + t.tag + " "
+	for _, n := range t.nodes {
+		s += n.Tree()
+	}
+	return s + ")"
+}
+
+func (t *tree) Value() string {
+	s := new(strings.Builder)
+	for _, n := range t.nodes {
+		s.WriteString(n.Value())
+	}
+	return s.String()
+}
+
+func (t *tree) SetTag(tag string) {
+	t.tag = tag
+}
+
+func (t *tree) Tag() string {
+	return t.tag
+}
+
+func (t *tree) Find(tag string, recurse ...bool) []gositter.SyntaxTree {
+	r := len(recurse) > 0 && recurse[0]
+	matches := make([]gositter.SyntaxTree, 0)
+	for _, n := range t.nodes {
+		if n.Tag() == tag {
+			matches = append(matches, n)
+			if r {
+				matches = append(matches, n.Find(tag)...)
+			}
+		} else {
+			matches = append(matches, n.Find(tag)...)
+		}
+	}
+	return matches
+}
+
+func (t *tree) Nodes() []gositter.SyntaxTree {
+	return t.nodes
+}
+
+// A leaf of a tree built after parsing, ie part of a delimiter run.
+type leaf struct {
+	tag   string
+	value string
+}
+
+func (l *leaf) Tree() string {
+	return "(" + l.tag + " " + l.value + ")"
+}
+
+func (l *leaf) Value() string {
+	return l.value
+}
+
+func (l *leaf) SetTag(tag string) {
+	l.tag = tag
+}
+
+func (l *leaf) Tag() string {
+	return l.tag
+}
+
+func (l *leaf) Find(string, ...bool) []gositter.SyntaxTree {
+	return []gositter.SyntaxTree{}
+}
+
+func (l *leaf) Nodes() []gositter.SyntaxTree {
+	return []gositter.SyntaxTree{}
+}
+
+// Returns the tree with the delimiter runs of each inline container paired
+// into em, strong and del nodes: the opening delimiter, the content and the
+// closing delimiter. The text of links is parsed here too.
+//
+// The grammar only matches the runs, matching nested emphasis in it is
+// exponential when it fails, since the parser backtracks without remembering
+// what failed.
+func pairEmphasis(t gositter.SyntaxTree) gositter.SyntaxTree {
+	if t.Tag() == "linktext" {
+		// Link text that can not be parsed is shown as is.
+		inner, err := linkTextGrammar.Parse(t.Value())
+		if err != nil {
+			return t
+		}
+		return &tree{tag: t.Tag(), nodes: pairRuns(inlineItems(inner))}
+	}
+	nodes := t.Nodes()
+	if len(nodes) == 0 {
+		return t
+	}
+	if slices.Contains(inlineContainers, t.Tag()) {
+		return &tree{tag: t.Tag(), nodes: pairRuns(inlineItems(t))}
+	}
+	paired := make([]gositter.SyntaxTree, len(nodes))
+	for i, n := range nodes {
+		paired[i] = pairEmphasis(n)
+	}
+	return &tree{tag: t.Tag(), nodes: paired}
+}
+
+// Returns the items of the inline container, without the rules grouping them.
+func inlineItems(t gositter.SyntaxTree) []gositter.SyntaxTree {
+	items := make([]gositter.SyntaxTree, 0)
+	for _, n := range t.Nodes() {
+		if slices.Contains(inlineGroups, n.Tag()) && len(n.Nodes()) > 0 {
+			items = append(items, inlineItems(n)...)
+		} else {
+			items = append(items, pairEmphasis(n))
+		}
+	}
+	return items
+}
+
+// An item of an inline container, or what is left of a delimiter run.
+type piece struct {
+	item gositter.SyntaxTree
+	// The delimiters of the run that are not paired yet.
+	delims   string
+	canOpen  bool
+	canClose bool
+}
+
+// Returns the item, or the delimiters that were not paired as text.
+func (p *piece) items() []gositter.SyntaxTree {
+	if p.item != nil {
+		return []gositter.SyntaxTree{p.item}
+	}
+	if p.delims == "" {
+		return nil
+	}
+	return []gositter.SyntaxTree{&leaf{tag: "delim", value: p.delims}}
+}
+
+// Pairs the delimiter runs of the items, like CommonMark: each run that can
+// close emphasis is paired with the nearest run of the same delimiter before
+// it that can open emphasis, and the runs between them are text. Runs can
+// open if they are followed by text, and close if they follow text, so
+// "5 * 3 * 2" stays text.
+func pairRuns(items []gositter.SyntaxTree) []gositter.SyntaxTree {
+	pieces := make([]*piece, len(items))
+	for i, item := range items {
+		if item.Tag() != "delim" {
+			pieces[i] = &piece{item: item}
+			continue
+		}
+		p := &piece{delims: item.Value()}
+		if i+1 < len(items) {
+			c, _ := utf8.DecodeRuneInString(items[i+1].Value())
+			p.canOpen = c != utf8.RuneError && !unicode.IsSpace(c)
+		}
+		if i > 0 {
+			c, _ := utf8.DecodeLastRuneInString(items[i-1].Value())
+			p.canClose = c != utf8.RuneError && !unicode.IsSpace(c)
+		}
+		// Only "~~" is a strikethrough delimiter.
+		if p.delims[0] == '~' && p.delims != "~~" {
+			p.canOpen, p.canClose = false, false
+		}
+		pieces[i] = p
+	}
+	// The indexes of the runs that can open emphasis, the nearest last.
+	openers := make([]int, 0)
+	for i := 0; i < len(pieces); i++ {
+		closer := pieces[i]
+		if closer.item != nil {
+			continue
+		}
+		for closer.canClose && closer.delims != "" {
+			o := len(openers) - 1
+			for o >= 0 && pieces[openers[o]].delims[0] != closer.delims[0] {
+				o--
+			}
+			if o < 0 {
+				break
+			}
+			j := openers[o]
+			opener := pieces[j]
+			n := 1
+			if opener.delims[0] == '~' || len(opener.delims) >= 2 && len(closer.delims) >= 2 {
+				n = 2
+			}
+			delim := opener.delims[len(opener.delims)-n:]
+			content := make([]gositter.SyntaxTree, 0)
+			for _, p := range pieces[j+1 : i] {
+				content = append(content, p.items()...)
+			}
+			tag := emphasisTags[delim]
+			emphasis := &tree{tag: tag, nodes: []gositter.SyntaxTree{
+				&leaf{tag: "delim", value: delim},
+				&tree{tag: tag + "inner", nodes: content},
+				&leaf{tag: "delim", value: closer.delims[:n]},
+			}}
+			opener.delims = opener.delims[:len(opener.delims)-n]
+			closer.delims = closer.delims[n:]
+			// The pieces between them are replaced by the emphasis, and the
+			// openers in it can no longer be paired.
+			pieces = append(pieces[:j+1], append([]*piece{{item: emphasis}}, pieces[i:]...)...)
+			i = j + 2
+			openers = openers[:o+1]
+			if opener.delims == "" {
+				openers = openers[:o]
+			}
+		}
+		if closer.canOpen && closer.delims != "" {
+			openers = append(openers, i)
+		}
+	}
+	paired := make([]gositter.SyntaxTree, 0, len(items))
+	for _, p := range pieces {
+		paired = append(paired, p.items()...)
+	}
+	return paired
+}
@@ -0,0 +1,261 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/samuellando/gositter"
+)
+
+// An error parsing or rendering a block of markdown.
+type ParseError struct {
+	// The position of the error, both starting at 1.
+	Line   int
+	Column int
+	// The source line containing the error.
+	Snippet string
+	// The grammar rule of the block that failed.
+	Rule    string
+	Message string
+	// The underlying error, if any, it is not kept when the error is cached.
+	Err error `json:"-"`
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s on line %d, column %d (%s): '%s'", e.Message, e.Line, e.Column, e.Rule, e.Snippet)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// The errors of a document rendered in lenient mode.
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Options for parsing and rendering markdown.
+type Options struct {
+	// Blocks that can not be parsed or rendered are rendered as escaped text
+	// instead of failing, the errors are returned as ParseErrors along with
+	// the html.
+	Lenient bool
+}
+
+func Lenient(o *Options) {
+	o.Lenient = true
+}
+
+// A part of the document, tree is nil if it could not be parsed.
+type segment struct {
+	offset int
+	source string
+	tree   gositter.SyntaxTree
+}
+
+// The block rules, by the text they start with. Used to describe parse errors
+// since the grammar does not report which rule failed.
+var blockPrefixes = []struct {
+	prefix string
+	rule   string
+}{
+	{"```", "codeblock"},
+	{"$$", "mathblock"},
+	{"|", "table"},
+	{">", "blockquote"},
+	{"#", "header"},
+	{"[^", "footnotedef"},
+	{"[[toc]]", "toc"},
+	{"{{<", "shortcodeblock"},
+	{"[", "linkdef"},
+	{"- ", "list"},
+	{"* ", "list"},
+	{"+ ", "list"},
+}
+
+// The blank lines between blocks.
+var blankLines = regexp.MustCompile(`\n(?:\r?\n)+`)
+
+// The first line of a code block, or of a list item or continuation.
+var (
+	fenceLine = regexp.MustCompile("^```[^`\r\n]*(?:\r?\n|$)")
+	listLine  = regexp.MustCompile(`^(?:[ \t]|[-*+][ \t]|\d{1,9}[.)][ \t])`)
+)
+
+// Parses the markdown, starting at offset in the full source.
+//
+// The markdown is parsed up to a blank line at a time, since the errors of the
+// grammar include the remaining input, parsing it all at once takes quadratic
+// time. The blocks that continue after a blank line are parsed with it.
+//
+// In lenient mode the block that fails to parse is split out as a segment
+// without a tree, and parsing continues before and after it.
+func parseSegments(full string, md string, offset int, lenient bool) ([]segment, ParseErrors) {
+	segments := make([]segment, 0)
+	var errs ParseErrors
+	for md != "" {
+		end := len(md)
+		if loc := blankLines.FindStringIndex(md); loc != nil {
+			end = loc[1]
+		}
+		tree, err := G.Parse(md[:end])
+		for err == nil && end < len(md) && continues(tree, md[end:]) {
+			next := len(md)
+			if loc := blankLines.FindStringIndex(md[end:]); loc != nil {
+				next = end + loc[1]
+			}
+			end = next
+			tree, err = G.Parse(md[:end])
+		}
+		if err == nil {
+			segments = append(segments, segment{offset: offset, source: md[:end], tree: pairEmphasis(tree)})
+		} else {
+			segs, segErrs := recoverSegments(full, md[:end], offset, lenient, err)
+			if !lenient {
+				return nil, segErrs
+			}
+			segments = append(segments, segs...)
+			errs = append(errs, segErrs...)
+		}
+		md = md[end:]
+		offset += end
+	}
+	return segments, errs
+}
+
+// Reports whether the last block of the tree continues after the blank lines
+// that follow it: a code or math block that is not closed yet is parsed as a
+// paragraph, and lists continue with the items after them.
+func continues(tree gositter.SyntaxTree, rest string) bool {
+	blocks := findAny(tree, "tag")
+	for _, block := range blocks {
+		if block.Nodes()[0].Tag() != "p" {
+			continue
+		}
+		if v := block.Value(); fenceLine.MatchString(v) || strings.HasPrefix(v, "$$") {
+			return true
+		}
+	}
+	return len(blocks) > 0 && blocks[len(blocks)-1].Nodes()[0].Tag() == "list" && listLine.MatchString(rest)
+}
+
+// Returns the segments of the markdown that failed to parse with err.
+func recoverSegments(full string, md string, offset int, lenient bool, err error) ([]segment, ParseErrors) {
+	fail := failureOffset(md, err)
+	// The block is the paragraph containing the failure.
+	start := strings.LastIndex(md[:fail], "\n") + 1
+	end := len(md)
+	if i := strings.Index(md[fail:], "\n\n"); i >= 0 {
+		end = fail + i + 1
+	}
+	rule := "p"
+	for _, bp := range blockPrefixes {
+		if strings.HasPrefix(md[start:], bp.prefix) {
+			rule = bp.rule
+			break
+		}
+	}
+	message := "Unexpected end of input"
+	if fail < len(md) {
+		c, _ := utf8.DecodeRuneInString(md[fail:])
+		message = fmt.Sprintf("Unexpected character %q", c)
+	}
+	perr := parseErrorAt(full, offset+fail, rule, message, err)
+	if !lenient {
+		return nil, ParseErrors{perr}
+	}
+	before, errs := parseSegments(full, md[:start], offset, lenient)
+	segments := append(before, segment{offset: offset + start, source: md[start:end]})
+	errs = append(errs, perr)
+	after, afterErrs := parseSegments(full, md[end:], offset+end, lenient)
+	return append(segments, after...), append(errs, afterErrs...)
+}
+
+// The grammar only reports the remaining input, in the message of the error.
+func failureOffset(md string, err error) int {
+	const prefix = "Remaining: "
+	msg := err.Error()
+	if !strings.HasPrefix(msg, prefix) {
+		return 0
+	}
+	msg = msg[len(prefix):]
+	// The remainder is followed by the parsed tree, which starts with "(root".
+	for i := 0; i < len(msg); {
+		j := strings.Index(msg[i:], " (root")
+		if j < 0 {
+			break
+		}
+		if rest := msg[:i+j]; strings.HasSuffix(md, rest) {
+			return len(md) - len(rest)
+		}
+		i += j + 1
+	}
+	return 0
+}
+
+func parseErrorAt(source string, offset int, rule, message string, err error) *ParseError {
+	line, column := lineColumn(source, offset)
+	return &ParseError{
+		Line:    line,
+		Column:  column,
+		Snippet: snippet(source, offset),
+		Rule:    rule,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// Returns the line and column of the offset in the source, both starting at 1.
+func lineColumn(source string, offset int) (int, int) {
+	before := source[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndex(before, "\n")
+	return line, column
+}
+
+// The source line containing the offset, shortened if it is too long.
+func snippet(source string, offset int) string {
+	const maxLen = 80
+	start := strings.LastIndex(source[:offset], "\n") + 1
+	end := strings.Index(source[offset:], "\n")
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += offset
+	}
+	line := strings.TrimRight(source[start:end], "\r")
+	if len(line) > maxLen {
+		line = strings.ToValidUTF8(line[:maxLen], "") + "…"
+	}
+	return line
+}
+
+// Returns the error as a ParseError located at the block t.
+func (r *renderer) blockError(t gositter.SyntaxTree, err error) *ParseError {
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		return perr
+	}
+	rule := t.Tag()
+	if nodes := t.Nodes(); len(nodes) > 0 {
+		rule = nodes[0].Tag()
+	}
+	r.position(t)
+	offset := r.offsets[t]
+	message := err.Error()
+	var refErr *ReferenceError
+	if errors.As(err, &refErr) {
+		offset = refErr.offset
+		message = refErr.message()
+	}
+	return parseErrorAt(r.source, offset, rule, message, err)
+}
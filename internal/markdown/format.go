@@ -0,0 +1,96 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+var listMarker = regexp.MustCompile(`^([ \t]*)(?:[-*+]|(\d{1,9})[.)])[ \t]+`)
+
+// Formats the markdown into its canonical form.
+//
+// Blocks are separated by a single blank line, list markers are "-" and "1.",
+// code fences have no space before the info string and trailing whitespace is
+// removed outside of code blocks. Front matter is kept as is.
+func Format(md string) (string, error) {
+	frontMatter, content := splitFrontMatter(md)
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	r, err := newRenderer(content)
+	if err != nil {
+		return "", err
+	}
+	s := new(strings.Builder)
+	if frontMatter != "" {
+		s.WriteString(strings.TrimRight(frontMatter, "\r\n") + "\n")
+		if content != "" {
+			s.WriteString("\n")
+		}
+	}
+	prev := ""
+	for _, seg := range r.segments {
+		for _, block := range findAny(seg.tree, "tag") {
+			kind := block.Nodes()[0].Tag()
+			if prev != "" {
+				// Definitions are kept together.
+				if kind == prev && (kind == "linkdef" || kind == "footnotedef") {
+					s.WriteString("\n")
+				} else {
+					s.WriteString("\n\n")
+				}
+			}
+			s.WriteString(formatBlock(kind, strings.TrimRight(block.Value(), "\n")))
+			prev = kind
+		}
+	}
+	if prev != "" {
+		s.WriteString("\n")
+	}
+	return s.String(), nil
+}
+
+func formatBlock(kind, block string) string {
+	lines := strings.Split(block, "\n")
+	switch kind {
+	case "codeblock":
+		// Only the fences are formatted, the code is kept as is.
+		lines[0] = "```" + strings.Join(strings.Fields(strings.TrimPrefix(lines[0], "```")), " ")
+		last := len(lines) - 1
+		lines[last] = strings.TrimRight(lines[last], " \t")
+		return strings.Join(lines, "\n")
+	case "list":
+		formatted := make([]string, 0, len(lines))
+		for _, line := range lines {
+			if m := listMarker.FindStringSubmatch(line); m != nil {
+				marker := "-"
+				if m[2] != "" {
+					marker = m[2] + "."
+				}
+				// The space after the marker is kept even for empty items.
+				line = m[1] + marker + " " + strings.TrimRight(line[len(m[0]):], " \t")
+			} else {
+				line = strings.TrimRight(line, " \t")
+			}
+			// Consecutive blank lines are collapsed.
+			if line == "" && len(formatted) > 0 && formatted[len(formatted)-1] == "" {
+				continue
+			}
+			formatted = append(formatted, line)
+		}
+		return strings.Join(formatted, "\n")
+	default:
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " \t")
+		}
+		return strings.Join(lines, "\n")
+	}
+}
+
+// Splits the raw front matter from the content, the front matter is empty if
+// there is none.
+func splitFrontMatter(md string) (string, string) {
+	fm, content, err := ParseFrontMatter(md)
+	if fm == nil || err != nil {
+		return "", md
+	}
+	return md[:len(md)-len(content)], content
+}
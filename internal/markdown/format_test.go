@@ -0,0 +1,38 @@
+package markdown
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	md := "# Title  \ntext\n\n\n\n* one\n*   two\n  + three\n\n\n1) a\n2) b\n``` go  {linenos}\nx  \n```\n[a]: /x\n[b]: /y\n"
+	expected := "# Title\n\ntext\n\n- one\n- two\n  - three\n\n1. a\n2. b\n\n```go {linenos}\nx  \n```\n\n[a]: /x\n[b]: /y\n"
+	formatted, err := Format(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted != expected {
+		t.Fatalf("Expected %q, got %q", expected, formatted)
+	}
+	again, err := Format(formatted)
+	if err != nil || again != formatted {
+		t.Fatalf("Expected formatting to be idempotent, got %q", again)
+	}
+}
+
+func TestFormatFrontMatter(t *testing.T) {
+	formatted, err := Format("---\ntitle: Kept  \nunknown: key\n---\nBody  \r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted != "---\ntitle: Kept  \nunknown: key\n---\n\nBody\n" {
+		t.Fatalf("Expected the front matter to be kept, got %q", formatted)
+	}
+}
+
+func TestFormatEmpty(t *testing.T) {
+	formatted, err := Format("")
+	if err != nil || formatted != "" {
+		t.Fatalf("Expected an empty document, got %q %v", formatted, err)
+	}
+}
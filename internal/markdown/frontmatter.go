@@ -0,0 +1,267 @@
+package markdown
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document metadata from a front matter block at the top of the markdown,
+// either yaml between "---" lines or toml between "+++" lines.
+type FrontMatter struct {
+	// The id of the document the markdown was exported from, or 0.
+	Id      int64
+	Title   string
+	Tags    []string
+	Created time.Time
+	Summary string
+	Slug    string
+	Draft   bool
+	Cover   string
+	// The publication status ie "scheduled", and when to publish.
+	Status    string
+	PublishAt time.Time
+}
+
+var dateFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Splits the front matter from the markdown, and returns the remaining content.
+//
+// Markdown without front matter is returned as is with a nil FrontMatter.
+// Only the simple forms of yaml and toml are supported: one key per line,
+// quoted or bare strings, booleans, dates and lists of strings. Unknown keys
+// are ignored.
+func ParseFrontMatter(md string) (*FrontMatter, string, error) {
+	md = strings.TrimPrefix(md, "\ufeff")
+	lines := strings.SplitAfter(md, "\n")
+	delim := strings.TrimRight(lines[0], "\r\n")
+	if delim != "---" && delim != "+++" {
+		return nil, md, nil
+	}
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], "\r\n") == delim {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, md, fmt.Errorf("Front matter is not closed, expected '%s'", delim)
+	}
+	sep := ":"
+	if delim == "+++" {
+		sep = "="
+	}
+	fm := new(FrontMatter)
+	for i := 1; i < end; i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, sep)
+		if !ok {
+			return nil, md, fmt.Errorf("Invalid front matter on line %d: '%s'", i+1, line)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		// A yaml list, with an item on each of the following lines.
+		if value == "" && sep == ":" {
+			items := make([]string, 0)
+			for i+1 < end && strings.HasPrefix(strings.TrimSpace(lines[i+1]), "- ") {
+				i++
+				item := strings.TrimPrefix(strings.TrimSpace(lines[i]), "- ")
+				s, err := parseFrontMatterString(item)
+				if err != nil {
+					return nil, md, fmt.Errorf("Invalid front matter value on line %d: '%s'", i+1, item)
+				}
+				items = append(items, s)
+			}
+			value = "[" + quoteAll(items) + "]"
+		}
+		if err := fm.set(key, value); err != nil {
+			return nil, md, fmt.Errorf("Invalid front matter value for '%s' on line %d: %s", key, i+1, err)
+		}
+	}
+	content := strings.Join(lines[end+1:], "")
+	// The blank line separating the front matter from the content.
+	content = strings.TrimLeft(content, "\r\n")
+	return fm, content, nil
+}
+
+func (fm *FrontMatter) set(key, value string) error {
+	var err error
+	switch key {
+	case "id":
+		fm.Id, err = strconv.ParseInt(stripComment(value), 10, 64)
+	case "title":
+		fm.Title, err = parseFrontMatterString(value)
+	case "summary", "description":
+		fm.Summary, err = parseFrontMatterString(value)
+	case "slug":
+		fm.Slug, err = parseFrontMatterString(value)
+	case "cover", "image":
+		fm.Cover, err = parseFrontMatterString(value)
+	case "draft":
+		fm.Draft, err = strconv.ParseBool(stripComment(value))
+	case "status":
+		fm.Status, err = parseFrontMatterString(value)
+	case "created", "date":
+		var s string
+		s, err = parseFrontMatterString(value)
+		if err == nil {
+			fm.Created, err = parseDate(s)
+		}
+	case "publish_at", "publishdate":
+		var s string
+		s, err = parseFrontMatterString(value)
+		if err == nil {
+			fm.PublishAt, err = parseDate(s)
+		}
+	case "tags":
+		fm.Tags, err = parseFrontMatterList(value)
+	}
+	return err
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, format := range dateFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not a date", s)
+}
+
+// Parses a double quoted, single quoted or bare string.
+func parseFrontMatterString(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		end := closingQuote(value)
+		if end < 0 {
+			return "", fmt.Errorf("unclosed string %s", value)
+		}
+		return strconv.Unquote(value[:end+1])
+	case strings.HasPrefix(value, "'"):
+		end := closingQuote(value)
+		if end < 0 {
+			return "", fmt.Errorf("unclosed string %s", value)
+		}
+		return strings.ReplaceAll(value[1:end], "''", "'"), nil
+	default:
+		return stripComment(value), nil
+	}
+}
+
+// Parses a list of strings ie [a, "b c"], or a single string as a list.
+func parseFrontMatterList(value string) ([]string, error) {
+	value = stripComment(value)
+	if !strings.HasPrefix(value, "[") {
+		s, err := parseFrontMatterString(value)
+		if err != nil || s == "" {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+	if !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("unclosed list %s", value)
+	}
+	rest := strings.TrimSpace(value[1 : len(value)-1])
+	items := make([]string, 0)
+	for rest != "" {
+		var item string
+		if strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, "'") {
+			end := closingQuote(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed string %s", rest)
+			}
+			item, rest = rest[:end+1], rest[end+1:]
+		} else {
+			item, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+		s, err := parseFrontMatterString(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, s)
+		rest = strings.TrimSpace(rest)
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+	}
+	return items, nil
+}
+
+// Returns the index of the quote closing the string at the start of s, or -1.
+func closingQuote(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case quote == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func stripComment(value string) string {
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+func quoteAll(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = strconv.Quote(item)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Returns the markdown with the front matter prepended as yaml, empty fields
+// are omitted.
+func (fm FrontMatter) Prepend(md string) string {
+	s := new(strings.Builder)
+	s.WriteString("---\n")
+	if fm.Id != 0 {
+		fmt.Fprintf(s, "id: %d\n", fm.Id)
+	}
+	fmt.Fprintf(s, "title: %s\n", strconv.Quote(fm.Title))
+	if len(fm.Tags) > 0 {
+		fmt.Fprintf(s, "tags: [%s]\n", quoteAll(fm.Tags))
+	}
+	if !fm.Created.IsZero() {
+		fmt.Fprintf(s, "created: %s\n", fm.Created.Format(time.RFC3339))
+	}
+	if fm.Summary != "" {
+		fmt.Fprintf(s, "summary: %s\n", strconv.Quote(fm.Summary))
+	}
+	if fm.Slug != "" {
+		fmt.Fprintf(s, "slug: %s\n", strconv.Quote(fm.Slug))
+	}
+	if fm.Draft {
+		s.WriteString("draft: true\n")
+	}
+	if fm.Status != "" {
+		fmt.Fprintf(s, "status: %s\n", fm.Status)
+	}
+	if !fm.PublishAt.IsZero() {
+		fmt.Fprintf(s, "publish_at: %s\n", fm.PublishAt.Format(time.RFC3339))
+	}
+	if fm.Cover != "" {
+		fmt.Fprintf(s, "cover: %s\n", strconv.Quote(fm.Cover))
+	}
+	s.WriteString("---\n\n")
+	s.WriteString(md)
+	return s.String()
+}
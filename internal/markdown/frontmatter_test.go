@@ -0,0 +1,111 @@
+package markdown
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrontMatterYaml(t *testing.T) {
+	md := "---\ntitle: \"Hello: world\"\ntags: [go, 'web dev']\ncreated: 2024-03-01\nsummary: A short post # a comment\ndraft: true\ncover: /asset/cover.png\n---\n\n# Content\n"
+	fm, content, err := ParseFrontMatter(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.Title != "Hello: world" {
+		t.Fatalf("Expected the quoted title, got '%s'", fm.Title)
+	}
+	if !slices.Equal(fm.Tags, []string{"go", "web dev"}) {
+		t.Fatalf("Expected 2 tags, got %v", fm.Tags)
+	}
+	if !fm.Created.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the created date, got %s", fm.Created)
+	}
+	if fm.Summary != "A short post" || !fm.Draft || fm.Cover != "/asset/cover.png" {
+		t.Fatalf("Expected summary, draft and cover, got %+v", fm)
+	}
+	if content != "# Content\n" {
+		t.Fatalf("Expected the front matter to be removed, got '%s'", content)
+	}
+}
+
+func TestFrontMatterYamlList(t *testing.T) {
+	fm, _, err := ParseFrontMatter("---\ntags:\n  - one\n  - \"two\"\nslug: my-post\n---\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(fm.Tags, []string{"one", "two"}) || fm.Slug != "my-post" {
+		t.Fatalf("Expected a tag list and a slug, got %+v", fm)
+	}
+}
+
+func TestFrontMatterToml(t *testing.T) {
+	fm, content, err := ParseFrontMatter("+++\ntitle = \"Toml\"\ndate = 2023-01-02T03:04:05Z\ntags = [\"a\", \"b\"]\ndraft = false\nunknown = 1\n+++\nBody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.Title != "Toml" || len(fm.Tags) != 2 || fm.Created.Year() != 2023 || fm.Draft {
+		t.Fatalf("Expected the toml fields, got %+v", fm)
+	}
+	if content != "Body" {
+		t.Fatalf("Expected the body, got '%s'", content)
+	}
+}
+
+func TestNoFrontMatter(t *testing.T) {
+	fm, content, err := ParseFrontMatter("# Title\n---\n")
+	if err != nil || fm != nil || content != "# Title\n---\n" {
+		t.Fatalf("Expected the markdown to be unchanged, got %v %q %v", fm, content, err)
+	}
+}
+
+func TestFrontMatterErrors(t *testing.T) {
+	_, _, err := ParseFrontMatter("---\ntitle: a\n")
+	if err == nil || !strings.Contains(err.Error(), "not closed") {
+		t.Fatalf("Expected an unclosed error, got %v", err)
+	}
+	_, _, err = ParseFrontMatter("---\ntitle: a\ndraft: maybe\n---\n")
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("Expected an error on line 3, got %v", err)
+	}
+}
+
+func TestFrontMatterRoundTrip(t *testing.T) {
+	fm := FrontMatter{
+		Id:      12,
+		Title:   `A "quoted" title`,
+		Tags:    []string{"one", "two, three"},
+		Created: time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC),
+		Summary: "Summary",
+		Slug:    "slug",
+		Draft:   true,
+		Cover:   "/cover.png",
+	}
+	parsed, content, err := ParseFrontMatter(fm.Prepend("Body\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "Body\n" {
+		t.Fatalf("Expected the body, got '%s'", content)
+	}
+	if parsed.Id != fm.Id || parsed.Title != fm.Title || !slices.Equal(parsed.Tags, fm.Tags) || !parsed.Created.Equal(fm.Created) ||
+		parsed.Summary != fm.Summary || parsed.Slug != fm.Slug || parsed.Draft != fm.Draft || parsed.Cover != fm.Cover {
+		t.Fatalf("Expected %+v, got %+v", fm, parsed)
+	}
+}
+
+func TestFrontMatterStatus(t *testing.T) {
+	fm := FrontMatter{
+		Title:     "Scheduled",
+		Status:    "scheduled",
+		PublishAt: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	parsed, _, err := ParseFrontMatter(fm.Prepend(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Status != "scheduled" || !parsed.PublishAt.Equal(fm.PublishAt) {
+		t.Fatalf("Expected %+v, got %+v", fm, parsed)
+	}
+}
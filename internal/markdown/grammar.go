@@ -4,7 +4,7 @@ import (
 	. "github.com/samuellando/gositter"
 )
 
-var G = CreateGrammar("root", map[string]Expression{
+var rules = map[string]Expression{
 	"root": Repeat1(
 		Choice(
 			Ref("tag"),
@@ -13,8 +13,13 @@ var G = CreateGrammar("root", map[string]Expression{
 		Ref("header"),
 		Ref("blockquote"),
 		Ref("codeblock"),
-		Ref("ul"),
-		Ref("ol"),
+		Ref("mathblock"),
+		Ref("footnotedef"),
+		Ref("linkdef"),
+		Ref("table"),
+		Ref("list"),
+		Ref("toc"),
+		Ref("shortcodeblock"),
 		Ref("p")),
 
 	"header": Choice(
@@ -33,6 +38,9 @@ var G = CreateGrammar("root", map[string]Expression{
 	"h5": Seq(Terminal("##### "), Ref("span")),
 	"h6": Seq(Terminal("###### "), Ref("span")),
 
+	// Replaced with the table of contents.
+	"toc": Seq(Terminal("[[toc]]"), Optional(Ref("newline"))),
+
 	"p": Ref("lines"),
 	"lines": Choice(
 		Seq(
@@ -41,42 +49,137 @@ var G = CreateGrammar("root", map[string]Expression{
 			Ref("lines")),
 		Seq(Ref("inner"))),
 	"inner": Repeat1(Choice(
+		Ref("text"),
+		Ref("shortcode"),
+		Ref("footnoteref"),
 		Ref("a"),
 		Ref("img"),
-		Ref("textchar"))),
-	"text": Repeat1(Ref("textchar")),
+		Ref("inline"),
+		Ref("textchar"),
+		Ref("backtick"))),
 	"textchar": Choice(
 		Ref("char"),
 		Ref("whitespace")),
+	// A run of text that can not start any markup, matched at once instead of
+	// character by character, the characters that can are matched by the
+	// alternatives after it.
+	"text":       Regex("[^\\f\\r\\n`*_~\\[\\]!<\\\\$&{]+"),
 	"span":       Seq(Ref("inner")),
 	"char":       Regex("[^\\s`]"),
-	"whitespace": Regex(` |\t`),
+	"backtick":   Terminal("`"),
+	"whitespace": Regex(`[ \t]`),
+	"spaces":     Regex(`[ \t]+`),
 	"newline": Choice(
 		Terminal("\n"),
 		Terminal("\r\n"),
 	),
 
+	// Inline markup. The runs of emphasis delimiters are paired after parsing,
+	// see pairEmphasis.
+	"inline": Choice(
+		Ref("code"),
+		Ref("math"),
+		Ref("delim"),
+		Ref("escape"),
+		Ref("html")),
+	"delim": Regex(`(?:\*+|~+)`),
+	// An inline html tag, only the elements in AllowedElements are rendered.
+	"html": Regex(`</?[A-Za-z][A-Za-z0-9]*(?:[ \t]+[^\s=/>]+(?:[ \t]*=[ \t]*(?:"[^"\r\n]*"|'[^'\r\n]*'|[^\s"'=<>` + "`" + `]+))?)*[ \t]*/?>`),
+	"code": Choice(
+		Seq(
+			Terminal("``"),
+			Ref("doublecodetext"),
+			Terminal("``")),
+		Seq(
+			Terminal("`"),
+			Ref("codetext"),
+			Terminal("`"))),
+	"codetext":       Regex("[^`\\r\\n]+"),
+	"doublecodetext": Regex("(?:[^`\\r\\n]|`[^`\\r\\n])+"),
+	// Like emphasis, math may not start or end with whitespace so that
+	// "$5 and $10" stays text.
+	"math": Seq(
+		Terminal("$"),
+		Ref("mathtext"),
+		Terminal("$")),
+	"mathtext": Regex(`[^$\s](?:[^$\r\n]*[^$\s])?`),
+	// Any ascii punctuation can be escaped with a backslash.
+	"escape": Seq(
+		Terminal("\\"),
+		Regex("[!-/:-@\\[-`{-~]")),
+
 	"a": Seq(
 		Terminal("["),
-		Choice(
+		Optional(Choice(
 			Ref("img"),
-			Ref("alt")),
+			Ref("linktext"))),
 		Terminal("]"),
-		Optional(Seq(
-			Terminal("("),
-			Ref("href"),
-			Terminal(")")))),
+		Optional(Choice(
+			Ref("destination"),
+			Ref("ref")))),
 	"img": Seq(
 		Terminal("!["),
 		Ref("alt"),
 		Terminal("]"),
-		Optional(Seq(
-			Terminal("("),
-			Ref("href"),
-			Terminal(")"))),
+		Optional(Choice(
+			Ref("destination"),
+			Ref("ref"))),
 		Optional(Ref("params"))),
-	"alt":  Regex(`[^\]]*`),
-	"href": Regex(`[^\)]*`),
+	// The text of a link is matched at once, and parsed once the link has
+	// matched, so that a bracket that is never closed is not parsed again as
+	// link text from every position after it.
+	"linktext": Regex("(?:[^\\]\\\\`\\r\\n]|\\\\[^\\r\\n]|`[^`\\r\\n]*`)+"),
+	"linkinner": Repeat1(Choice(
+		Ref("inline"),
+		Ref("whitespace"),
+		Ref("linkchar"))),
+	"linkchar": Regex("[^\\s\\]`]"),
+	"alt":      Regex(`[^\]]*`),
+	// (href "title"), the href may contain spaces but not before a title.
+	"destination": Seq(
+		Terminal("("),
+		Ref("href"),
+		Optional(Seq(
+			Ref("spaces"),
+			Ref("title"))),
+		Optional(Ref("spaces")),
+		Terminal(")")),
+	"href": Regex(`(?:[^\s)]|[ \t]+[^\s)"'(])*`),
+
+	// A reference to a link definition, [text][] uses the text as the label.
+	"ref": Seq(
+		Terminal("["),
+		Optional(Ref("reflabel")),
+		Terminal("]")),
+	"reflabel": Ref("label"),
+	"label":    Regex(`[^\]\r\n]+`),
+	// [label]: url "title"
+	"linkdef": Seq(
+		Terminal("["),
+		Ref("label"),
+		Terminal("]:"),
+		Optional(Ref("spaces")),
+		Ref("url"),
+		Optional(Seq(
+			Ref("spaces"),
+			Ref("title"))),
+		Optional(Ref("spaces")),
+		Optional(Ref("newline"))),
+	"url":   Regex(`[^\s]+`),
+	"title": Regex(`(?:"[^"\r\n]*"|'[^'\r\n]*'|\([^)\r\n]*\))`),
+	"footnoteref": Seq(
+		Terminal("[^"),
+		Ref("label"),
+		Terminal("]")),
+	// [^label]: text
+	"footnotedef": Seq(
+		Terminal("[^"),
+		Ref("label"),
+		Terminal("]:"),
+		Optional(Ref("spaces")),
+		Ref("footnotetext"),
+		Optional(Ref("newline"))),
+	"footnotetext": Ref("inner"),
 
 	"params": Seq(
 		Terminal("{"),
@@ -90,27 +193,160 @@ var G = CreateGrammar("root", map[string]Expression{
 		Ref("param")),
 	"param": Regex("[^,}]*"),
 
+	"mathblock": Seq(
+		Terminal("$$"),
+		Ref("mathsource"),
+		Terminal("$$"),
+		Optional(Ref("newline"))),
+	"mathsource": Regex(`(?:[^$]|\$[^$])+`),
+
 	"blockquote": Seq(
 		Terminal(">"),
-		Ref("p")),
+		Optional(Ref("callout")),
+		Ref("quote")),
+	// GitHub style callouts: > [!NOTE]
+	"callout": Seq(
+		Optional(Ref("spaces")),
+		Terminal("[!"),
+		Ref("callouttype"),
+		Terminal("]"),
+		Optional(Ref("spaces")),
+		Optional(Seq(
+			Ref("newline"),
+			Ref("quotemarker")))),
+	"callouttype": Regex(`(?i)(?:note|tip|warning)`),
+	// Like a paragraph, the following lines may start with a marker.
+	"quote": Ref("quotelines"),
+	"quotelines": Choice(
+		Seq(
+			Ref("inner"),
+			Ref("newline"),
+			Optional(Ref("quotemarker")),
+			Ref("quotelines")),
+		Seq(Ref("inner"))),
+	"quotemarker": Regex(`>[ \t]?`),
+
+	// {{< name key=value key="a value" >}}, rendered by a registered shortcode.
+	"shortcode": Seq(
+		Terminal("{{<"),
+		Optional(Ref("spaces")),
+		Ref("shortcodename"),
+		Optional(Repeat(Seq(
+			Ref("spaces"),
+			Ref("shortcodearg")))),
+		Optional(Ref("spaces")),
+		Terminal(">}}")),
+	"shortcodename": Regex(`[A-Za-z][\w-]*`),
+	"shortcodearg": Seq(
+		Ref("argname"),
+		Terminal("="),
+		Ref("argvalue")),
+	"argname":  Regex(`[A-Za-z][\w-]*`),
+	"argvalue": Regex(`(?:"(?:[^"\\\r\n]|\\.)*"|'[^'\r\n]*'|[^\s"'>]+)`),
+	// A shortcode on its own line is not wrapped in a paragraph.
+	"shortcodeblock": Seq(
+		Ref("shortcode"),
+		Regex(`[ \t]*(?:\r?\n|$)`)),
+	// A fenced code block, with an optional info string: ```go {linenos,3-5}
 	"codeblock": Seq(
 		Terminal("```"),
+		Optional(Ref("info")),
 		Ref("newline"),
-		Repeat1(Seq(
-			Ref("text"),
-			Ref("newline"))),
+		Optional(Repeat(Ref("codeline"))),
 		Terminal("```"),
 		Optional(Ref("newline"))),
+	"info": Seq(
+		Optional(Ref("spaces")),
+		Optional(Ref("lang")),
+		Optional(Ref("spaces")),
+		Optional(Ref("params")),
+		Optional(Ref("spaces"))),
+	"lang": Regex(`[\w#+.-]+`),
+	"codeline": Seq(
+		Optional(Ref("linetext")),
+		Ref("newline")),
+	// Any line, except for the closing fence.
+	"linetext": Regex("(?m)(?:(?:[^`\\r\\n]|`[^`\\r\\n]|``[^`\\r\\n])[^\\r\\n]*|``?$)"),
 
-	"ul": Repeat1(
-		Seq(
-			Terminal("- "),
-			Ref("li"),
-			Optional(Ref("newline")))),
-	"ol": Repeat1(
-		Seq(
-			Regex(`\d*. `),
-			Ref("li"),
-			Optional(Ref("newline")))),
-	"li": Ref("span"),
-})
+	// GitHub style pipe tables, rows must start with a pipe.
+	"table": Seq(
+		Ref("thead"),
+		Ref("newline"),
+		Ref("tabledelim"),
+		Optional(Repeat(Seq(
+			Ref("newline"),
+			Ref("trow")))),
+		Optional(Ref("newline"))),
+	"thead": Ref("row"),
+	"trow":  Ref("row"),
+	"row": Seq(
+		Terminal("|"),
+		Repeat1(Seq(
+			Ref("cell"),
+			Terminal("|"))),
+		Optional(Ref("spaces")),
+		Optional(Ref("lastcell"))),
+	"cell":     Seq(Optional(Ref("cellinner"))),
+	"lastcell": Ref("cellinner"),
+	"cellinner": Repeat1(Choice(
+		Ref("celltext"),
+		Ref("footnoteref"),
+		Ref("a"),
+		Ref("img"),
+		Ref("inline"),
+		Ref("whitespace"),
+		Ref("cellchar"),
+		Ref("backtick"))),
+	"celltext": Regex("[^\\f\\r\\n|`*_~\\[\\]!<\\\\$&{]+"),
+	"cellchar": Regex("[^\\s|`]"),
+	"tabledelim": Seq(
+		Terminal("|"),
+		Repeat1(Seq(
+			Ref("align"),
+			Terminal("|"))),
+		Optional(Ref("spaces")),
+		Optional(Ref("lastalign"))),
+	"align":     Regex(`[ \t]*:?-+:?[ \t]*`),
+	"lastalign": Ref("align"),
+
+	// Lists are parsed line by line, the nesting is built from the indentation
+	// of each line when rendering.
+	"list": Seq(
+		Ref("listitem"),
+		Optional(Repeat(Choice(
+			Ref("listitem"),
+			Ref("listcontinuation"),
+			Ref("listgap"))))),
+	"listitem": Seq(
+		Optional(Ref("indent")),
+		Ref("marker"),
+		Optional(Ref("task")),
+		Optional(Ref("li")),
+		Optional(Ref("newline"))),
+	// An indented line that is not an item continues the previous item.
+	"listcontinuation": Seq(
+		Ref("indent"),
+		Ref("li"),
+		Optional(Ref("newline"))),
+	// Blank lines only belong to the list if it continues after them.
+	"listgap": Seq(
+		Repeat1(Seq(
+			Optional(Ref("spaces")),
+			Ref("newline"))),
+		Choice(
+			Ref("listitem"),
+			Ref("listcontinuation"))),
+	"indent": Regex(`[ \t]+`),
+	"marker": Choice(
+		Ref("bullet"),
+		Ref("ordinal")),
+	"bullet":  Regex(`[-*+][ \t]+`),
+	"ordinal": Regex(`\d{1,9}[.)][ \t]+`),
+	"task":    Regex(`(?m)\[[ xX]\](?:[ \t]+|$)`),
+	"li":      Ref("inner"),
+}
+
+var G = CreateGrammar("root", rules)
+
+// Parses the text of links, see pairEmphasis.
+var linkTextGrammar = CreateGrammar("linkinner", rules)
@@ -0,0 +1,217 @@
+// This package provides a small regex based syntax highlighter used to render
+// fenced code blocks on the server.
+//
+// Code is split into tokens by a per language lexer, each token is given a
+// class (keyword, string, comment...) which is rendered as a span with the
+// class "hl-<class>". Text that no rule matches is emitted as is.
+package highlight
+
+import (
+	"html/template"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Token classes, rendered as "hl-<class>".
+const (
+	Plain     = ""
+	Keyword   = "kw"
+	Type      = "typ"
+	String    = "str"
+	Number    = "num"
+	Comment   = "com"
+	Function  = "fn"
+	Literal   = "lit"
+	Variable  = "var"
+	Tag       = "tag"
+	Attribute = "attr"
+	Key       = "key"
+)
+
+// A piece of highlighted code.
+type Token struct {
+	Class string
+	Value string
+}
+
+// A lexing rule, the pattern is matched at the current position.
+//
+// If classes is set, each capture group of the pattern is emitted as its own
+// token with the corresponding class, otherwise the whole match uses class.
+// If sub is set, the match is tokenized again with the sub lexer.
+type rule struct {
+	pattern *regexp.Regexp
+	class   string
+	classes []string
+	sub     *lexer
+}
+
+type lexer struct {
+	rules []rule
+	// Identifiers not matched by a rule are looked up in words.
+	ident *regexp.Regexp
+	words map[string]string
+	// Lookup words in lower case.
+	caseInsensitive bool
+	// Identifiers followed by a '(' are functions.
+	calls bool
+}
+
+func r(class, pattern string) rule {
+	return rule{pattern: regexp.MustCompile(`\A(?:` + pattern + `)`), class: class}
+}
+
+func groups(pattern string, classes ...string) rule {
+	return rule{pattern: regexp.MustCompile(`\A(?:` + pattern + `)`), classes: classes}
+}
+
+func nested(pattern string, sub *lexer) rule {
+	return rule{pattern: regexp.MustCompile(`\A(?:` + pattern + `)`), sub: sub}
+}
+
+func wordsOf(class string, words string, into map[string]string) map[string]string {
+	if into == nil {
+		into = make(map[string]string)
+	}
+	for _, w := range strings.Fields(words) {
+		into[w] = class
+	}
+	return into
+}
+
+// Returns true if there is a lexer for the language (or one of its aliases).
+func Supported(lang string) bool {
+	_, ok := languages[strings.ToLower(lang)]
+	return ok
+}
+
+// Split the code into tokens. Unsupported languages return a single plain token.
+func Tokenize(lang, code string) []Token {
+	l, ok := languages[strings.ToLower(lang)]
+	if !ok {
+		return []Token{{Class: Plain, Value: code}}
+	}
+	return l.tokenize(code)
+}
+
+// Highlight the code and return the html of each line.
+//
+// Tokens spanning multiple lines (like block comments) are split so that every
+// line is self contained, which allows lines to be wrapped individually.
+func Lines(lang, code string) []template.HTML {
+	code = strings.ReplaceAll(code, "\r\n", "\n")
+	lines := make([]template.HTML, 0)
+	line := new(strings.Builder)
+	for _, tok := range Tokenize(lang, code) {
+		parts := strings.Split(tok.Value, "\n")
+		for i, part := range parts {
+			if i > 0 {
+				lines = append(lines, template.HTML(line.String()))
+				line.Reset()
+			}
+			writeToken(line, Token{Class: tok.Class, Value: part})
+		}
+	}
+	return append(lines, template.HTML(line.String()))
+}
+
+func writeToken(w *strings.Builder, tok Token) {
+	if tok.Value == "" {
+		return
+	}
+	if tok.Class == Plain {
+		w.WriteString(template.HTMLEscapeString(tok.Value))
+		return
+	}
+	w.WriteString(`<span class="hl-`)
+	w.WriteString(tok.Class)
+	w.WriteString(`">`)
+	w.WriteString(template.HTMLEscapeString(tok.Value))
+	w.WriteString(`</span>`)
+}
+
+func (l *lexer) tokenize(code string) []Token {
+	tokens := make([]Token, 0)
+	emit := func(class, value string) {
+		if value == "" {
+			return
+		}
+		// Merge adjacent tokens of the same class to keep the output small.
+		if n := len(tokens); n > 0 && tokens[n-1].Class == class {
+			tokens[n-1].Value += value
+			return
+		}
+		tokens = append(tokens, Token{Class: class, Value: value})
+	}
+	rest := code
+	for len(rest) > 0 {
+		matched := false
+		for _, ru := range l.rules {
+			loc := ru.pattern.FindStringSubmatchIndex(rest)
+			if loc == nil || loc[1] == 0 {
+				continue
+			}
+			match := rest[:loc[1]]
+			switch {
+			case ru.sub != nil:
+				for _, tok := range ru.sub.tokenize(match) {
+					emit(tok.Class, tok.Value)
+				}
+			case ru.classes != nil:
+				end := 0
+				for i, class := range ru.classes {
+					start, stop := loc[2*(i+1)], loc[2*(i+1)+1]
+					if start < 0 {
+						continue
+					}
+					emit(Plain, match[end:start])
+					emit(class, match[start:stop])
+					end = stop
+				}
+				emit(Plain, match[end:])
+			default:
+				emit(ru.class, match)
+			}
+			rest = rest[loc[1]:]
+			matched = true
+			break
+		}
+		if matched {
+			continue
+		}
+		if l.ident != nil {
+			if loc := l.ident.FindStringIndex(rest); loc != nil && loc[1] > 0 {
+				word := rest[:loc[1]]
+				rest = rest[loc[1]:]
+				emit(l.classify(word, rest), word)
+				continue
+			}
+		}
+		// Consume a single character, or a run of word characters so that
+		// keywords are not matched in the middle of a word.
+		_, n := utf8.DecodeRuneInString(rest)
+		if loc := wordRun.FindStringIndex(rest); loc != nil && loc[1] > 0 {
+			n = loc[1]
+		}
+		emit(Plain, rest[:n])
+		rest = rest[n:]
+	}
+	return tokens
+}
+
+var wordRun = regexp.MustCompile(`\A\w+`)
+
+func (l *lexer) classify(word, rest string) string {
+	key := word
+	if l.caseInsensitive {
+		key = strings.ToLower(word)
+	}
+	if class, ok := l.words[key]; ok {
+		return class
+	}
+	if l.calls && strings.HasPrefix(rest, "(") {
+		return Function
+	}
+	return Plain
+}
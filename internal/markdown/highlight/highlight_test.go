@@ -0,0 +1,81 @@
+package highlight
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// Each testdata/<lang>.input is highlighted and compared to testdata/<lang>.golden
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("No golden inputs found")
+	}
+	for _, input := range inputs {
+		lang := strings.TrimSuffix(filepath.Base(input), ".input")
+		t.Run(lang, func(t *testing.T) {
+			if !Supported(lang) {
+				t.Fatalf("Language %s should be supported", lang)
+			}
+			code, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := Lines(lang, string(code))
+			got := new(strings.Builder)
+			for _, line := range lines {
+				got.WriteString(string(line))
+				got.WriteString("\n")
+			}
+			golden := strings.TrimSuffix(input, ".input") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got.String()), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != string(expected) {
+				t.Fatalf("Output does not match %s, got:\n%s", golden, got.String())
+			}
+		})
+	}
+}
+
+func TestUnsupportedIsEscaped(t *testing.T) {
+	lines := Lines("brainfuck", "<b>\n&")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	if lines[0] != "&lt;b&gt;" || lines[1] != "&amp;" {
+		t.Fatalf("Expected escaped lines, got %v", lines)
+	}
+}
+
+func TestMultilineTokensAreSplit(t *testing.T) {
+	lines := Lines("go", "/* one\ntwo */ x")
+	if lines[0] != `<span class="hl-com">/* one</span>` {
+		t.Fatalf("First line should be a closed comment span, got %s", lines[0])
+	}
+	if lines[1] != `<span class="hl-com">two */</span> x` {
+		t.Fatalf("Second line should be a closed comment span, got %s", lines[1])
+	}
+}
+
+func TestAliases(t *testing.T) {
+	for _, lang := range []string{"golang", "Go", "bash", "sh", "postgres", "yml", "xml"} {
+		if !Supported(lang) {
+			t.Fatalf("Expected %s to be supported", lang)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package highlight
+
+import "regexp"
+
+const (
+	doubleQuoted = `"(?:[^"\\\n]|\\.)*"`
+	singleQuoted = `'(?:[^'\\\n]|\\.)*'`
+	number       = `-?(?:0[xX][0-9a-fA-F_]+|\d[\d_]*(?:\.\d+)?(?:[eE][+-]?\d+)?)\b`
+	identifier   = `\A[A-Za-z_]\w*`
+)
+
+var golang = &lexer{
+	rules: []rule{
+		r(Comment, `//[^\n]*`),
+		r(Comment, `/\*[\s\S]*?\*/`),
+		r(String, doubleQuoted),
+		r(String, "`[^`]*`"),
+		r(String, singleQuoted),
+		r(Number, number),
+	},
+	ident: regexp.MustCompile(identifier),
+	words: wordsOf(Keyword, `break case chan const continue default defer else
+		fallthrough for func go goto if import interface map package range return
+		select struct switch type var`,
+		wordsOf(Type, `any bool byte comparable complex64 complex128 error float32
+			float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32
+			uint64 uintptr`,
+			wordsOf(Literal, `true false nil iota`, nil))),
+	calls: true,
+}
+
+var sql = &lexer{
+	rules: []rule{
+		r(Comment, `--[^\n]*`),
+		r(Comment, `/\*[\s\S]*?\*/`),
+		r(String, `'(?:[^']|'')*'`),
+		r(Plain, `"[^"]*"`),
+		r(Variable, `\$\d+|@\w+|:\w+`),
+		r(Number, number),
+	},
+	ident: regexp.MustCompile(identifier),
+	words: wordsOf(Keyword, `add all alter always and as asc begin between by cascade case
+		check column commit constraint create cross database default delete desc
+		distinct do drop else end exists foreign from full generated group having
+		identity if in index inner insert intersect into is join key left like limit
+		not null offset on or order outer primary references rename replace restrict
+		returning right rollback schema select set table then to transaction union
+		unique update using values view when where with`,
+		wordsOf(Type, `bigint bigserial boolean bool bytea char date decimal double
+			float int integer interval json jsonb numeric real serial smallint text
+			time timestamp timestamptz uuid varchar`,
+			wordsOf(Literal, `true false`, nil))),
+	caseInsensitive: true,
+	calls:           true,
+}
+
+var shell = &lexer{
+	rules: []rule{
+		r(Comment, `#[^\n]*`),
+		r(String, `'[^']*'`),
+		r(String, `"(?:[^"\\]|\\.)*"`),
+		r(Variable, `\$\{[^}\n]*\}|\$\w+|\$[@#?$!*-]`),
+		r(Attribute, `--?[A-Za-z][\w-]*`),
+		r(Number, `\d+\b`),
+	},
+	ident: regexp.MustCompile(`\A[A-Za-z_][\w.-]*`),
+	words: wordsOf(Keyword, `if then else elif fi for while until do done case esac
+		in function select return exit export local readonly declare unset shift
+		source alias`,
+		wordsOf(Function, `echo cd printf read test eval exec trap wait kill set
+			pwd sudo`, nil)),
+}
+
+var htmlTag = &lexer{
+	rules: []rule{
+		r(Tag, `</?[A-Za-z][\w:-]*|/?>`),
+		groups(`([A-Za-z_:@][\w:.@-]*)(\s*=\s*)("[^"]*"|'[^']*'|[^\s>]+)`, Attribute, Plain, String),
+		r(Attribute, `[A-Za-z_:@][\w:.@-]*`),
+	},
+}
+
+var html = &lexer{
+	rules: []rule{
+		r(Comment, `<!--[\s\S]*?-->`),
+		r(Keyword, `<![A-Za-z][^>]*>`),
+		nested(`</?[A-Za-z][^>]*>?`, htmlTag),
+		r(Variable, `&#?\w+;`),
+	},
+}
+
+var json = &lexer{
+	rules: []rule{
+		groups(`(`+doubleQuoted+`)(\s*:)`, Key, Plain),
+		r(String, doubleQuoted),
+		r(Number, number),
+	},
+	ident: regexp.MustCompile(identifier),
+	words: wordsOf(Literal, `true false null`, nil),
+}
+
+var yaml = &lexer{
+	rules: []rule{
+		r(Comment, `#[^\n]*`),
+		r(Keyword, `(?m)^(?:---|\.\.\.)$`),
+		groups(`([A-Za-z_][\w .-]*?|`+doubleQuoted+`|`+singleQuoted+`)(:)(?:[ \t]|\n|$)`, Key, Plain),
+		r(String, doubleQuoted),
+		r(String, singleQuoted),
+		r(Variable, `[&*][\w-]+`),
+		r(Number, number),
+	},
+	ident: regexp.MustCompile(identifier),
+	words: wordsOf(Literal, `true false yes no on off null`, nil),
+}
+
+// Languages and their aliases, keys are in lower case.
+var languages = map[string]*lexer{
+	"go":         golang,
+	"golang":     golang,
+	"sql":        sql,
+	"postgres":   sql,
+	"postgresql": sql,
+	"psql":       sql,
+	"sh":         shell,
+	"bash":       shell,
+	"shell":      shell,
+	"zsh":        shell,
+	"console":    shell,
+	"html":       html,
+	"xml":        html,
+	"svg":        html,
+	"json":       json,
+	"yaml":       yaml,
+	"yml":        yaml,
+}
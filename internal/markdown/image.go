@@ -0,0 +1,76 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/samuellando/gositter"
+)
+
+// The intrinsic size and the responsive variants of an image.
+type ImageInfo struct {
+	Width  int
+	Height int
+	// The candidates of the srcset attribute, ie "/asset/a.png?w=320 320w".
+	Srcset []string
+	// The sizes attribute, the width the image is displayed at.
+	Sizes string
+}
+
+// Returns the details of an image from its source, or nil if there are none
+// ie for external images. Images are rendered as is if it is nil.
+var ResolveImage func(src string) *ImageInfo
+
+type img struct {
+	Src     string
+	Alt     string
+	Title   string
+	Height  string
+	Width   string
+	Srcset  string
+	Sizes   string
+	Caption string
+}
+
+func (r *renderer) parseImage(t gositter.SyntaxTree) (*img, error) {
+	img := new(img)
+	img.Alt = t.Find("alt")[0].Value()
+	if refs := t.Find("ref"); len(refs) > 0 {
+		ld, err := r.resolve(refs[0], img.Alt)
+		if err != nil {
+			return nil, err
+		}
+		img.Src, img.Title = ld.Href, ld.Title
+	} else if hrefs := t.Find("href"); len(hrefs) > 0 {
+		img.Src = hrefs[0].Value()
+		if titles := t.Find("title"); len(titles) > 0 {
+			img.Title = unquoteTitle(titles[0].Value())
+		}
+	}
+	img.Src = sanitizeUrl(img.Src)
+	params := t.Find("param")
+	if len(params) >= 1 {
+		img.Height = params[0].Value()
+	}
+	if len(params) >= 2 {
+		img.Width = params[1].Value()
+	}
+	if ResolveImage == nil || img.Src == "" {
+		return img, nil
+	}
+	if info := ResolveImage(img.Src); info != nil {
+		img.Srcset = strings.Join(info.Srcset, ", ")
+		img.Sizes = info.Sizes
+		// The intrinsic size avoids layout shifts while the image loads.
+		if img.Height == "" && img.Width == "" && info.Width > 0 && info.Height > 0 {
+			img.Width = strconv.Itoa(info.Width)
+			img.Height = strconv.Itoa(info.Height)
+		}
+	}
+	return img, nil
+}
+
+// Removes the quotes or parentheses around a title.
+func unquoteTitle(title string) string {
+	return title[1 : len(title)-1]
+}
@@ -0,0 +1,81 @@
+// This package renders tex math to inline svg using canvas, it is used as
+// the markdown math renderer.
+//
+// Rendered expressions are cached by their source, in memory and optionally
+// in the database.
+package latex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image/color"
+	"time"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/renderers/svg"
+	"samuellando.com/internal/cache"
+)
+
+// The color of the rendered math, the theme's text color.
+var Color = color.RGBA{R: 0xeb, G: 0xdb, B: 0xb2, A: 0xff}
+
+// The number of rendered expressions kept in memory.
+const CacheSize = 1024
+
+// Returns a math renderer for markdown.RenderMath, db can be nil to only cache
+// in memory. The renderer is safe for concurrent use.
+func Renderer(db *sql.DB) func(string, bool) (template.HTML, error) {
+	c := cache.NewLRU(CacheSize, func(co *cache.CacheOptions) {
+		co.MaxAge = 30 * 24 * time.Hour
+		co.Db = db
+	})
+	return func(tex string, display bool) (template.HTML, error) {
+		data, err := c.Get(cacheKey(tex, display), func() ([]byte, error) {
+			return ToSvg(tex, display)
+		})
+		return template.HTML(data), err
+	}
+}
+
+func cacheKey(tex string, display bool) string {
+	key := "$" + tex + "$"
+	if display {
+		key = "$" + key + "$"
+	}
+	hasher := sha256.New()
+	hasher.Write([]byte(key))
+	return "math:" + base64.URLEncoding.EncodeToString(hasher.Sum(nil))
+}
+
+// Typesets the expression and returns it as an svg element.
+func ToSvg(tex string, display bool) ([]byte, error) {
+	if display {
+		tex = `\displaystyle ` + tex
+	}
+	p, err := canvas.ParseLaTeX("$" + tex + "$")
+	if err != nil {
+		return nil, fmt.Errorf("Could not compile math: %s", err)
+	}
+	c := canvas.New(0, 0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFill(Color)
+	ctx.DrawPath(0, 0, p)
+	c.Fit(0.5)
+
+	buf := new(bytes.Buffer)
+	s := svg.New(buf, c.W, c.H, nil)
+	c.RenderTo(s)
+	if err := s.Close(); err != nil {
+		return nil, err
+	}
+	// Only keep the svg element, so it can be inlined in the page.
+	out := buf.Bytes()
+	if i := bytes.Index(out, []byte("<svg")); i > 0 {
+		out = out[i:]
+	}
+	return out, nil
+}
@@ -0,0 +1,57 @@
+package latex
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestToSvg(t *testing.T) {
+	out, err := ToSvg(`\frac{a}{b} + x^2`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(out, []byte("<svg")) || !bytes.HasSuffix(bytes.TrimSpace(out), []byte("</svg>")) {
+		t.Fatalf("Expected an svg element, got %s", out)
+	}
+}
+
+func TestToSvgError(t *testing.T) {
+	_, err := ToSvg(`\frac{a}{`, true)
+	if err == nil {
+		t.Fatal("Expected an error for an unbalanced expression")
+	}
+}
+
+func TestRendererCaches(t *testing.T) {
+	render := Renderer(nil)
+	first, err := render(`e^{i\pi}`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := render(`e^{i\pi}`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("Expected the same output for the same expression")
+	}
+}
+
+func TestRendererConcurrent(t *testing.T) {
+	render := Renderer(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				if _, err := render(fmt.Sprintf("x^%d", j), false); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,108 @@
+package markdown
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// The longest line allowed by Lint, code blocks and tables are not checked.
+const MaxLineLength = 120
+
+// A style issue found by Lint.
+type LintIssue struct {
+	// The position of the issue, both starting at 1.
+	Line   int
+	Column int
+	// The check that found the issue, ie "image-alt".
+	Check   string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", i.Line, i.Column, i.Message, i.Check)
+}
+
+// Checks the markdown for style issues: images without alt text, skipped
+// heading levels, empty links, trailing whitespace and long lines.
+//
+// The issues are sorted by position, errors are returned as a *ParseError.
+func Lint(md string) ([]LintIssue, error) {
+	frontMatter, content := splitFrontMatter(md)
+	doc, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	issues := make([]LintIssue, 0)
+	add := func(pos Position, check, message string) {
+		issues = append(issues, LintIssue{Line: pos.Line, Column: pos.Column, Check: check, Message: message})
+	}
+	level := 0
+	doc.Walk(func(n *Node) bool {
+		switch n.Kind {
+		case HeadingNode:
+			if level > 0 && n.Level > level+1 {
+				add(n.Pos, "heading-level", fmt.Sprintf("Heading level %d follows level %d", n.Level, level))
+			}
+			level = n.Level
+		case ImageNode:
+			if strings.TrimSpace(n.Alt) == "" {
+				add(n.Pos, "image-alt", "Image has no alt text")
+			}
+		case LinkNode:
+			if n.Href == "" {
+				add(n.Pos, "empty-link", "Link has no destination")
+			}
+			if len(n.Children) == 0 {
+				add(n.Pos, "empty-link", "Link has no text")
+			}
+		}
+		return true
+	})
+	issues = append(issues, lintLines(content)...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	// Positions are relative to the document, including its front matter.
+	offset := strings.Count(frontMatter, "\n")
+	for i := range issues {
+		issues[i].Line += offset
+	}
+	return issues, nil
+}
+
+// The checks on the source lines.
+func lintLines(md string) []LintIssue {
+	issues := make([]LintIssue, 0)
+	code := false
+	for i, line := range strings.Split(md, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "```") {
+			code = !code
+		}
+		if trimmed := strings.TrimRight(line, " \t"); trimmed != line {
+			issues = append(issues, LintIssue{
+				Line:    i + 1,
+				Column:  utf8.RuneCountInString(trimmed) + 1,
+				Check:   "trailing-whitespace",
+				Message: "Line has trailing whitespace",
+			})
+		}
+		if code || strings.HasPrefix(line, "|") {
+			continue
+		}
+		if length := utf8.RuneCountInString(line); length > MaxLineLength {
+			issues = append(issues, LintIssue{
+				Line:    i + 1,
+				Column:  MaxLineLength + 1,
+				Check:   "line-length",
+				Message: fmt.Sprintf("Line is %d characters long, the maximum is %d", length, MaxLineLength),
+			})
+		}
+	}
+	return issues
+}
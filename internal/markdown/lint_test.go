@@ -0,0 +1,41 @@
+package markdown
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	md := "---\ntitle: a\n---\n# Title \n\n### Skipped\n\n![](/x.png) [](/y) [text]()\n\n```\n" +
+		strings.Repeat("x", MaxLineLength+1) + "\n```\n\n" + strings.Repeat("y ", MaxLineLength/2+1) + "\n"
+	issues, err := Lint(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"4:8: Line has trailing whitespace (trailing-whitespace)",
+		"6:1: Heading level 3 follows level 1 (heading-level)",
+		"8:1: Image has no alt text (image-alt)",
+		"8:13: Link has no text (empty-link)",
+		"8:20: Link has no destination (empty-link)",
+		"14:121: Line is 122 characters long, the maximum is 120 (line-length)",
+		"14:122: Line has trailing whitespace (trailing-whitespace)",
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %v", len(expected), issues)
+	}
+	for i, issue := range issues {
+		if issue.String() != expected[i] {
+			t.Fatalf("Expected '%s', got '%s'", expected[i], issue)
+		}
+	}
+}
+
+func TestLintParseError(t *testing.T) {
+	_, err := Lint("bad \f")
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected a parse error, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package markdown
+
+import (
+	"html/template"
+	"strings"
+)
+
+// Renders tex math, display is set for $$ blocks. When nil the source is
+// shown as code.
+var RenderMath func(tex string, display bool) (template.HTML, error)
+
+type formula struct {
+	Display bool
+	Source  string
+	Svg     template.HTML
+	Error   string
+}
+
+// An expression that fails to compile is shown in an error box with its
+// source, rather than failing the whole document.
+func renderMath(tex string, display bool) formula {
+	m := formula{Display: display, Source: strings.TrimSpace(tex)}
+	if RenderMath == nil {
+		return m
+	}
+	svg, err := RenderMath(m.Source, display)
+	if err != nil {
+		m.Error = err.Error()
+	} else {
+		m.Svg = svg
+	}
+	return m
+}
@@ -2,12 +2,16 @@ package markdown
 
 import (
 	"embed"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/samuellando/gositter"
 	"html/template"
+	"samuellando.com/internal/markdown/highlight"
 )
 
 //go:embed markdown_components
@@ -15,35 +19,190 @@ var embeded embed.FS
 
 var COMPONENTS, LOAD_ERR = template.New("").ParseFS(embeded, "markdown_components/*")
 
-func ToHtml(md string) (template.HTML, error) {
-	tree, err := G.Parse(md)
+// Changes whenever the same markdown renders to different html, so that
+// cached html can be invalidated.
+const RendererVersion = 5
+
+// Renders the markdown as html.
+//
+// Errors are returned as a *ParseError, or as ParseErrors along with the
+// html in lenient mode.
+func ToHtml(md string, opts ...func(*Options)) (template.HTML, error) {
+	r, err := newRenderer(md, opts...)
+	if err != nil {
+		return "", err
+	}
+	return r.render()
+}
+
+// A rendered document, along with what was found while rendering it.
+type Rendered struct {
+	Html            template.HTML
+	TableOfContents []Heading
+	// The errors of the blocks rendered as text in lenient mode.
+	Errors ParseErrors
+}
+
+// Renders the markdown like ToHtml, and returns its table of contents and the
+// errors of lenient mode with the html, instead of as an error.
+func Render(md string, opts ...func(*Options)) (Rendered, error) {
+	r, err := newRenderer(md, opts...)
+	if err != nil {
+		return Rendered{}, err
+	}
+	html, err := r.render()
+	var errs ParseErrors
+	if err != nil && !errors.As(err, &errs) {
+		return Rendered{}, err
+	}
+	return Rendered{
+		Html:            html,
+		TableOfContents: r.toc(),
+		Errors:          errs,
+	}, nil
+}
+
+// Holds the state of rendering a single document.
+type renderer struct {
+	source   string
+	segments []segment
+	options  Options
+	// The errors of the blocks rendered as text in lenient mode.
+	errors ParseErrors
+	// The offset of each node in the source, built when first needed.
+	offsets map[gositter.SyntaxTree]int
+	// The allowed html elements opened in the tree being rendered, see
+	// sanitizeTag.
+	openTags []string
+	// The number of times each heading id was used.
+	ids           map[string]int
+	headings      []Heading
+	links         map[string]linkdef
+	footnoteDefs  map[string]gositter.SyntaxTree
+	footnotes     map[string]*footnote
+	footnoteOrder []*footnote
+}
+
+// Parses the markdown, and returns a renderer for it.
+func newRenderer(source string, opts ...func(*Options)) (*renderer, error) {
+	options := Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	segments, errs := parseSegments(source, source, 0, options.Lenient)
+	if !options.Lenient && len(errs) > 0 {
+		return nil, errs[0]
+	}
+	r := &renderer{
+		source:       source,
+		segments:     segments,
+		options:      options,
+		errors:       errs,
+		ids:          make(map[string]int),
+		links:        make(map[string]linkdef),
+		footnoteDefs: make(map[string]gositter.SyntaxTree),
+		footnotes:    make(map[string]*footnote),
+	}
+	for _, seg := range segments {
+		if seg.tree != nil {
+			r.collectDefinitions(seg.tree)
+		}
+	}
+	return r, nil
+}
+
+func (r *renderer) render() (template.HTML, error) {
+	s := new(strings.Builder)
+	for _, seg := range r.segments {
+		if seg.tree == nil {
+			err := COMPONENTS.ExecuteTemplate(s, "unparsed", seg.source)
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+		html, err := r.parseTree(seg.tree)
+		if err != nil {
+			return "", err
+		}
+		s.WriteString(string(html))
+	}
+	footnotes, err := r.renderFootnotes()
+	if err != nil {
+		return "", err
+	}
+	html, err := r.insertToc(template.HTML(s.String()) + footnotes)
 	if err != nil {
 		return "", err
 	}
-	return parseTree(tree)
+	if len(r.errors) > 0 {
+		return html, r.errors
+	}
+	return html, nil
 }
 
 type a struct {
 	Href  string
+	Title string
 	Inner template.HTML
+	// Links to other sites open in a new tab.
+	External bool
 }
 
-type img struct {
-	Src    string
-	Alt    string
-	Height string
-	Width  string
+type list struct {
+	Ordered bool
+	Start   int
+	Items   []*listItem
 }
 
-type list struct {
-	Lis []template.HTML
+type listItem struct {
+	Task       bool
+	Checked    bool
+	Paragraphs []template.HTML
+	// The rendered sub lists.
+	Lists template.HTML
+	lists []*list
+	// The lines of each paragraph.
+	paragraphs [][]gositter.SyntaxTree
+	// The indentation of the item's marker.
+	indent int
+}
+
+type table struct {
+	Head []cell
+	Rows [][]cell
+}
+
+type cell struct {
+	Align string
+	Html  template.HTML
+}
+
+type codeblock struct {
+	Lang        string
+	LineNumbers bool
+	Lines       []codeline
 }
 
-func parseTags(out io.Writer, t gositter.SyntaxTree) error {
+type codeline struct {
+	Number      int
+	Html        template.HTML
+	Highlighted bool
+}
+
+func (r *renderer) parseTags(out io.Writer, t gositter.SyntaxTree) error {
+	switch t.Tag() {
+	case "quotemarker":
+		// The markers of the following lines of a blockquote are not content.
+		return nil
+	case "html":
+		out.Write([]byte(r.sanitizeTag(t.Value())))
+		return nil
+	}
 	nodes := t.Nodes()
-	// If this is a leaf, just return it's value
+	// If this is a leaf, it's text
 	if len(nodes) == 0 {
-		out.Write([]byte(t.Value()))
+		out.Write([]byte(template.HTMLEscapeString(t.Value())))
 		return nil
 	}
 	// Otherwise check if there is an exiting template
@@ -51,68 +210,137 @@ func parseTags(out io.Writer, t gositter.SyntaxTree) error {
 	var data any
 	var err error
 	switch t.Tag() {
+	case "tag":
+		// Errors are located at the block, which is rendered as text in
+		// lenient mode.
+		s := new(strings.Builder)
+		for _, node := range nodes {
+			if err := r.parseTags(s, node); err != nil {
+				perr := r.blockError(t, err)
+				if !r.options.Lenient {
+					return perr
+				}
+				r.errors = append(r.errors, perr)
+				return COMPONENTS.ExecuteTemplate(out, "unparsed", t.Value())
+			}
+		}
+		out.Write([]byte(s.String()))
+		return nil
 	case "header":
 		sub := t.Find("span")[0]
 		tag = t.Nodes()[0].Tag()
-		data, err = parseTree(sub)
+		data, err = r.parseHeading(tag, sub)
+	case "toc":
+		out.Write([]byte(tocMarker))
+		return nil
 	case "blockquote":
-		sub := t.Find("p")[0]
 		tag = t.Tag()
-		data, err = parseTree(sub)
-	case "codeblock":
-		lines := t.Find("text")
-		inner := new(strings.Builder)
-		for _, line := range lines {
-			inner.Write([]byte(fmt.Sprintf("%s\n", line.Value())))
+		data, err = r.parseTree(t.Find("quote")[0])
+		if kinds := t.Find("callouttype"); len(kinds) > 0 && err == nil {
+			tag = "callout"
+			data = newCallout(kinds[0].Value(), data.(template.HTML))
+		}
+	case "quote":
+		// Rendered as the paragraph of the blockquote.
+		tag = "p"
+		data, err = r.parseTree(nodes[0])
+	case "shortcode":
+		html, err := renderShortcode(t)
+		if err != nil {
+			return err
 		}
+		out.Write([]byte(html))
+		return nil
+	case "codeblock":
 		tag = "codeblock"
-		data = inner.String()
+		data, err = parseCodeblock(t)
 	case "p", "span":
+		// An image with a caption on its own is rendered as a figure.
+		if imgs := t.Find("img"); t.Tag() == "p" && len(imgs) == 1 && strings.TrimSpace(t.Value()) == imgs[0].Value() {
+			img, err := r.parseImage(imgs[0])
+			if err != nil {
+				return err
+			}
+			if img.Title != "" {
+				img.Caption, img.Title = img.Title, ""
+				return COMPONENTS.ExecuteTemplate(out, "figure", img)
+			}
+		}
 		tag = t.Tag()
 		sub := t.Nodes()[0]
-		data, err = parseTree(sub)
+		data, err = r.parseTree(sub)
+	case "em", "strong", "del":
+		tag = t.Tag()
+		sub := t.Nodes()[1]
+		data, err = r.parseTree(sub)
+	case "code":
+		tag = "code"
+		// Either single or double backtick delimiters, the text is in the middle.
+		data = t.Nodes()[0].Nodes()[1].Value()
+	case "math", "mathblock":
+		tag = "math"
+		data = renderMath(t.Nodes()[1].Value(), t.Tag() == "mathblock")
+	case "escape":
+		out.Write([]byte(template.HTMLEscapeString(t.Nodes()[1].Value())))
+		return nil
 	case "a":
-		var inner template.HTML
-		ht := t.Find("href")[0]
-		imgs := t.Find("img")
-		if len(imgs) == 0 {
-			at := t.Find("alt")[0]
-			inner = template.HTML(at.Value())
-		} else {
-			inner, err = parseTree(imgs[0])
+		link := a{}
+		var text string
+		if imgs := t.Find("img"); len(imgs) > 0 {
+			link.Inner, err = r.parseTree(imgs[0])
+		} else if lts := t.Find("linktext"); len(lts) > 0 {
+			text = lts[0].Value()
+			link.Inner, err = r.parseTree(lts[0])
 		}
-		tag = "a"
-		data = a{Href: ht.Value(), Inner: inner}
-	case "img":
-		img := new(img)
-		img.Alt = t.Find("alt")[0].Value()
-		img.Src = t.Find("href")[0].Value()
-		params := t.Find("param")
-		if len(params) >= 1 {
-			img.Height = params[0].Value()
+		if err != nil {
+			return err
 		}
-		if len(params) >= 2 {
-			img.Width = params[1].Value()
+		// The link's own href or reference is always in the last node, an
+		// inner image has its own.
+		last := nodes[len(nodes)-1]
+		if refs := last.Find("ref"); len(refs) > 0 {
+			ld, err := r.resolve(refs[0], text)
+			if err != nil {
+				return err
+			}
+			link.Href, link.Title = ld.Href, ld.Title
+		} else if hts := last.Find("href"); len(hts) > 0 {
+			link.Href = hts[0].Value()
+			if titles := last.Find("title"); len(titles) > 0 {
+				link.Title = unquoteTitle(titles[0].Value())
+			}
 		}
+		link.Href = sanitizeUrl(link.Href)
+		link.External = externalUrl(link.Href)
+		tag = "a"
+		data = link
+	case "footnoteref":
+		tag = "footnoteref"
+		data, err = r.referenceFootnote(t)
+	case "linkdef", "footnotedef":
+		// Definitions are collected before rendering.
+		return nil
+	case "img":
 		tag = "img"
-		data = img
-	case "ul", "ol":
-		lis := t.Find("li")
-		list := new(list)
-		var inner template.HTML
-		for _, li := range lis {
-			inner, err = parseTree(li)
-			if err != nil {
-				break
+		data, err = r.parseImage(t)
+	case "table":
+		tag = "table"
+		data, err = r.parseTable(t)
+	case "list":
+		lists, err := parseList(t)
+		if err != nil {
+			return err
+		}
+		for _, l := range lists {
+			if err := r.renderList(out, l); err != nil {
+				return err
 			}
-			list.Lis = append(list.Lis, inner)
 		}
-		tag = t.Tag()
-		data = list
+		return nil
 	default:
 		// If there is no template, continue traversing the tree.
 		for _, node := range nodes {
-			err := parseTags(out, node)
+			err := r.parseTags(out, node)
 			if err != nil {
 				return err
 			}
@@ -126,12 +354,306 @@ func parseTags(out io.Writer, t gositter.SyntaxTree) error {
 	return COMPONENTS.ExecuteTemplate(out, tag, data)
 }
 
-func parseTree(t gositter.SyntaxTree) (template.HTML, error) {
+// Renders the cells of a table, every row must have as many columns as the
+// header and delimiter rows.
+func (r *renderer) parseTable(t gositter.SyntaxTree) (table, error) {
+	tbl := table{}
+	delim := t.Find("tabledelim")[0]
+	aligns := make([]string, 0)
+	for _, a := range append(delim.Find("align"), delim.Find("lastalign")...) {
+		aligns = append(aligns, alignment(a.Value()))
+	}
+	head, err := r.parseRow(t.Find("thead")[0], aligns)
+	if err != nil {
+		return tbl, err
+	}
+	if len(head) != len(aligns) {
+		return tbl, fmt.Errorf("Table header has %d columns but the delimiter row has %d: '%s'",
+			len(head), len(aligns), t.Find("thead")[0].Value())
+	}
+	tbl.Head = head
+	for i, row := range t.Find("trow") {
+		cells, err := r.parseRow(row, aligns)
+		if err != nil {
+			return tbl, err
+		}
+		// Reported at the row, not at the start of the table.
+		if len(cells) != len(aligns) {
+			r.position(row)
+			message := fmt.Sprintf("Table row %d has %d columns, expected %d", i+1, len(cells), len(aligns))
+			return tbl, parseErrorAt(r.source, r.offsets[row], "table", message, nil)
+		}
+		tbl.Rows = append(tbl.Rows, cells)
+	}
+	return tbl, nil
+}
+
+func (r *renderer) parseRow(row gositter.SyntaxTree, aligns []string) ([]cell, error) {
+	cells := make([]cell, 0)
+	for i, c := range append(row.Find("cell"), row.Find("lastcell")...) {
+		inner, err := r.parseTree(c)
+		if err != nil {
+			return nil, err
+		}
+		align := ""
+		if i < len(aligns) {
+			align = aligns[i]
+		}
+		cells = append(cells, cell{
+			Align: align,
+			Html:  template.HTML(strings.TrimSpace(string(inner))),
+		})
+	}
+	return cells, nil
+}
+
+// Builds the list tree from the list's lines.
+//
+// An item indented further than the previous one starts a sub list, indented
+// lines that are not items continue the last item with a lower indentation,
+// after a blank line they start a new paragraph. Changing between ordered and
+// unordered items at the same level starts a new list.
+func parseList(t gositter.SyntaxTree) ([]*list, error) {
+	roots := make([]*list, 0)
+	// The open lists, from the outer most to the inner most.
+	stack := make([]*list, 0)
+	gap := false
+	for _, line := range findAny(t, "listitem", "listcontinuation", "listgap") {
+		if line.Tag() == "listgap" {
+			gap = true
+			line = findAny(line, "listitem", "listcontinuation")[0]
+		}
+		indent := 0
+		if ind := line.Find("indent"); len(ind) > 0 {
+			indent = indentWidth(ind[0].Value())
+		}
+		lis := line.Find("li")
+		if line.Tag() == "listcontinuation" {
+			var item *listItem
+			for i := len(stack) - 1; i >= 0 && item == nil; i-- {
+				last := stack[i].Items[len(stack[i].Items)-1]
+				if last.indent < indent {
+					item = last
+				}
+			}
+			if item == nil {
+				return nil, fmt.Errorf("List continuation is not indented: '%s'", line.Value())
+			}
+			if gap || len(item.paragraphs) == 0 {
+				item.paragraphs = append(item.paragraphs, lis)
+			} else {
+				last := len(item.paragraphs) - 1
+				item.paragraphs[last] = append(item.paragraphs[last], lis...)
+			}
+			gap = false
+			continue
+		}
+		gap = false
+		item := &listItem{indent: indent}
+		if len(lis) > 0 {
+			item.paragraphs = append(item.paragraphs, lis)
+		}
+		if tasks := line.Find("task"); len(tasks) > 0 {
+			item.Task = true
+			item.Checked = strings.ContainsAny(tasks[0].Value(), "xX")
+		}
+		ordinals := line.Find("ordinal")
+		ordered := len(ordinals) > 0
+		// Close the lists nested deeper than this item.
+		for len(stack) > 0 && lastItem(stack[len(stack)-1]).indent > indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if lastItem(top).indent == indent && top.Ordered == ordered {
+				top.Items = append(top.Items, item)
+				continue
+			}
+			if lastItem(top).indent == indent {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		l := &list{Ordered: ordered, Start: 1, Items: []*listItem{item}}
+		if ordered {
+			digits := strings.TrimRight(ordinals[0].Value(), ".) \t")
+			start, err := strconv.Atoi(digits)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid list number '%s'", digits)
+			}
+			l.Start = start
+		}
+		if len(stack) == 0 {
+			roots = append(roots, l)
+		} else {
+			parent := lastItem(stack[len(stack)-1])
+			parent.lists = append(parent.lists, l)
+		}
+		stack = append(stack, l)
+	}
+	return roots, nil
+}
+
+func lastItem(l *list) *listItem {
+	return l.Items[len(l.Items)-1]
+}
+
+// Renders the list as a ul or ol, sub lists are rendered first.
+func (r *renderer) renderList(out io.Writer, l *list) error {
+	for _, item := range l.Items {
+		item.Paragraphs = make([]template.HTML, len(item.paragraphs))
+		for i, lines := range item.paragraphs {
+			html := make([]string, len(lines))
+			for j, li := range lines {
+				inner, err := r.parseTree(li)
+				if err != nil {
+					return err
+				}
+				html[j] = string(inner)
+			}
+			item.Paragraphs[i] = template.HTML(strings.Join(html, "\n"))
+		}
+		s := new(strings.Builder)
+		for _, sub := range item.lists {
+			if err := r.renderList(s, sub); err != nil {
+				return err
+			}
+		}
+		item.Lists = template.HTML(s.String())
+	}
+	tag := "ul"
+	if l.Ordered {
+		tag = "ol"
+	}
+	return COMPONENTS.ExecuteTemplate(out, tag, l)
+}
+
+// The width of leading whitespace, tabs count as 4 spaces.
+func indentWidth(s string) int {
+	width := 0
+	for _, c := range s {
+		if c == '\t' {
+			width += 4
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// Like Find, but matches any of the tags, in the order they appear.
+func findAny(t gositter.SyntaxTree, tags ...string) []gositter.SyntaxTree {
+	matches := make([]gositter.SyntaxTree, 0)
+	for _, n := range t.Nodes() {
+		if slices.Contains(tags, n.Tag()) {
+			matches = append(matches, n)
+		} else {
+			matches = append(matches, findAny(n, tags...)...)
+		}
+	}
+	return matches
+}
+
+// Converts a delimiter cell ie ":---:" into a css text-align value.
+func alignment(delim string) string {
+	delim = strings.TrimSpace(delim)
+	left := strings.HasPrefix(delim, ":")
+	right := strings.HasSuffix(delim, ":")
+	switch {
+	case left && right:
+		return "center"
+	case right:
+		return "right"
+	case left:
+		return "left"
+	default:
+		return ""
+	}
+}
+
+// Highlights the code block, and applies the options from the info string.
+//
+// Options are a comma separated list of line numbers or ranges to highlight
+// and "linenos" to show line numbers, ie ```go {linenos,3-5,8}
+func parseCodeblock(t gositter.SyntaxTree) (codeblock, error) {
+	cb := codeblock{}
+	if langs := t.Find("lang"); len(langs) > 0 {
+		cb.Lang = strings.ToLower(langs[0].Value())
+	}
+	lines := t.Find("codeline")
+	// The inclusive ranges of highlighted lines.
+	highlighted := make([][2]int, 0)
+	for _, param := range t.Find("param") {
+		opt := strings.TrimSpace(param.Value())
+		if opt == "" {
+			continue
+		}
+		if opt == "linenos" {
+			cb.LineNumbers = true
+			continue
+		}
+		from, to, err := parseLineRange(opt, len(lines))
+		if err != nil {
+			return cb, err
+		}
+		highlighted = append(highlighted, [2]int{from, to})
+	}
+	if len(lines) == 0 {
+		return cb, nil
+	}
+	code := make([]string, len(lines))
+	for i, line := range lines {
+		if text := line.Find("linetext"); len(text) > 0 {
+			code[i] = text[0].Value()
+		}
+	}
+	for i, html := range highlight.Lines(cb.Lang, strings.Join(code, "\n")) {
+		line := codeline{
+			Number: i + 1,
+			Html:   html,
+		}
+		for _, r := range highlighted {
+			if r[0] <= line.Number && line.Number <= r[1] {
+				line.Highlighted = true
+				break
+			}
+		}
+		cb.Lines = append(cb.Lines, line)
+	}
+	return cb, nil
+}
+
+// Parses "n" or "n-m" into an inclusive range of the lines of a code block,
+// the end of the range is clamped to the last line.
+func parseLineRange(s string, lines int) (int, int, error) {
+	start, end, isRange := strings.Cut(s, "-")
+	from, err := strconv.Atoi(strings.TrimSpace(start))
+	if err != nil || from < 1 {
+		return 0, 0, fmt.Errorf("Invalid code block option '%s'", s)
+	}
+	to := from
+	if isRange {
+		to, err = strconv.Atoi(strings.TrimSpace(end))
+		if err != nil || to < from {
+			return 0, 0, fmt.Errorf("Invalid code block line range '%s'", s)
+		}
+	}
+	if from > lines {
+		return 0, 0, fmt.Errorf("Code block line range '%s' is past its %d lines", s, lines)
+	}
+	return from, min(to, lines), nil
+}
+
+func (r *renderer) parseTree(t gositter.SyntaxTree) (template.HTML, error) {
 	if LOAD_ERR != nil {
 		var zero template.HTML
 		return zero, LOAD_ERR
 	}
+	// The html elements opened in the tree are closed in it.
+	outer := r.openTags
+	r.openTags = nil
+	defer func() { r.openTags = outer }()
 	s := new(strings.Builder)
-	err := parseTags(s, t)
+	err := r.parseTags(s, t)
+	s.WriteString(r.closeTags(0))
 	return template.HTML(s.String()), err
 }
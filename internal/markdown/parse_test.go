@@ -0,0 +1,581 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+)
+
+func render(t *testing.T, md string) string {
+	t.Helper()
+	html, err := ToHtml(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(html)
+}
+
+func TestInlineEmphasis(t *testing.T) {
+	html := render(t, "Some *italic*, **bold** and ~~struck~~ text")
+	if !strings.Contains(html, `<em class="md-em">italic</em>,`) {
+		t.Fatalf("Expected italic text, got %s", html)
+	}
+	if !strings.Contains(html, `<strong class="md-strong">bold</strong>`) {
+		t.Fatalf("Expected bold text, got %s", html)
+	}
+	if !strings.Contains(html, `<del class="md-del">struck</del>`) {
+		t.Fatalf("Expected struck text, got %s", html)
+	}
+}
+
+func TestInlineNesting(t *testing.T) {
+	html := render(t, "**bold *and italic***")
+	if !strings.Contains(html, `<strong class="md-strong">bold <em class="md-em">and italic</em></strong>`) {
+		t.Fatalf("Expected italic inside bold, got %s", html)
+	}
+	html = render(t, "[**bold** link](http://example.com)")
+	if !strings.Contains(html, `<a class="md-a" href="http://example.com" target="_blank" rel="noopener noreferrer"><strong class="md-strong">bold</strong> link</a>`) {
+		t.Fatalf("Expected bold inside link, got %s", html)
+	}
+	html = render(t, "**[bold link](http://example.com)** and ***both***")
+	if !strings.HasPrefix(html[strings.Index(html, "<strong"):], "<strong class=\"md-strong\">\n<a class=\"md-a\"") {
+		t.Fatalf("Expected a link inside bold, got %s", html)
+	}
+	if !strings.Contains(html, `<em class="md-em"><strong class="md-strong">both</strong></em>`) {
+		t.Fatalf("Expected bold inside italic, got %s", html)
+	}
+	html = render(t, "- item with `code`\n- *second*")
+	if !strings.Contains(html, `item with <code class="md-code">code</code>`) {
+		t.Fatalf("Expected code inside list item, got %s", html)
+	}
+}
+
+func TestInlineCode(t *testing.T) {
+	html := render(t, "Call `fmt.Println(\"<b>\")` now")
+	if !strings.Contains(html, `<code class="md-code">fmt.Println(&#34;&lt;b&gt;&#34;)</code>`) {
+		t.Fatalf("Expected escaped inline code, got %s", html)
+	}
+	html = render(t, "``a `tick` inside``")
+	if !strings.Contains(html, "<code class=\"md-code\">a `tick` inside</code>") {
+		t.Fatalf("Expected double backtick code, got %s", html)
+	}
+	html = render(t, "The lone ` backtick")
+	if !strings.Contains(html, "The lone ` backtick") {
+		t.Fatalf("Expected lone backtick as text, got %s", html)
+	}
+}
+
+func TestInlineEscapes(t *testing.T) {
+	html := render(t, `\*not italic\* and \~~not struck\~~ and \`+"`not code`")
+	if strings.Contains(html, "<em") || strings.Contains(html, "<del") || strings.Contains(html, "<code") {
+		t.Fatalf("Escaped delimiters should not produce markup, got %s", html)
+	}
+	if !strings.Contains(html, "*not italic*") {
+		t.Fatalf("Expected escaped characters to be output, got %s", html)
+	}
+}
+
+func TestUnclosedDelimiters(t *testing.T) {
+	html := render(t, "5 * 3 and ~ and **open")
+	if strings.Contains(html, "<em") || strings.Contains(html, "<strong") {
+		t.Fatalf("Unclosed delimiters should be text, got %s", html)
+	}
+}
+
+// Unclosed delimiters and brackets must not make the parser backtrack
+// exponentially, one stray line would hang rendering.
+func TestUnclosedDelimitersParseTime(t *testing.T) {
+	inputs := []string{
+		strings.Repeat("*[", 16),
+		strings.Repeat("*[**~~", 8),
+		strings.Repeat("**~~*", 8),
+		strings.Repeat("[*[**", 8),
+		strings.Repeat("![*", 16),
+		strings.Repeat("*[**~~", 100),
+	}
+	for _, md := range inputs {
+		start := time.Now()
+		ToHtml(md)
+		if d := time.Since(start); d > time.Second {
+			t.Fatalf("Expected %q to parse in under a second, took %s", md, d)
+		}
+	}
+}
+
+func TestManyBlocksParseTime(t *testing.T) {
+	md := strings.Repeat("Some *text* with a [link](/a).\n\n## Heading\n\n- item\n- item\n\n", 300)
+	start := time.Now()
+	ToHtml(md)
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("Expected 900 blocks to parse in under 5 seconds, took %s", d)
+	}
+}
+
+func TestBlocksAcrossBlankLines(t *testing.T) {
+	html := render(t, "```go\na := 1\n\n\nb := 2\n```\n\n$$\nx\n\ny\n$$\n\n- a\n\n- b\n\n  c\n\nAfter")
+	if strings.Count(html, "<pre") != 1 || !strings.Contains(html, "b") {
+		t.Fatalf("Expected one code block, got %s", html)
+	}
+	if strings.Count(html, "md-math-display") != 1 {
+		t.Fatalf("Expected one math block, got %s", html)
+	}
+	if strings.Count(html, "<ul") != 1 || strings.Count(html, "<li") != 2 {
+		t.Fatalf("Expected one list of 2 items, got %s", html)
+	}
+	if strings.Index(html, "After") < strings.Index(html, "</ul>") {
+		t.Fatalf("Expected a paragraph after the list, got %s", html)
+	}
+}
+
+func TestCodeblockHighlighting(t *testing.T) {
+	html := render(t, "```go\nfunc main() {\n\tfmt.Println(\"`hi`\")\n}\n```\n")
+	if !strings.Contains(html, `<pre class="md-codeblock md-codeblock-go">`) {
+		t.Fatalf("Expected the language class, got %s", html)
+	}
+	if !strings.Contains(html, `<span class="hl-kw">func</span>`) {
+		t.Fatalf("Expected highlighted keywords, got %s", html)
+	}
+	if strings.Contains(html, "md-lineno") {
+		t.Fatalf("Line numbers should be off by default, got %s", html)
+	}
+}
+
+func TestCodeblockOptions(t *testing.T) {
+	html := render(t, "```sql {linenos,2-3}\nSELECT 1;\n\nSELECT 2;\nSELECT 3;\n```")
+	if strings.Count(html, `<span class="md-lineno">`) != 4 {
+		t.Fatalf("Expected 4 line numbers, got %s", html)
+	}
+	if strings.Count(html, "md-codeline-hl") != 2 {
+		t.Fatalf("Expected 2 highlighted lines, got %s", html)
+	}
+	if !strings.Contains(html, `<span class="md-codeline md-codeline-hl"><span class="md-lineno">2</span></span>`) {
+		t.Fatalf("Expected the empty second line to be highlighted, got %s", html)
+	}
+}
+
+func TestCodeblockPlain(t *testing.T) {
+	html := render(t, "```\n<b>not bold</b>\n```")
+	if !strings.Contains(html, "&lt;b&gt;not bold&lt;/b&gt;") {
+		t.Fatalf("Expected escaped code, got %s", html)
+	}
+}
+
+func TestCodeblockInvalidOption(t *testing.T) {
+	_, err := ToHtml("```go {5-2}\nx\n```")
+	if err == nil {
+		t.Fatal("Expected an error for an invalid line range")
+	}
+}
+
+func TestCodeblockLargeRange(t *testing.T) {
+	html := render(t, "```go {1-200000000}\nx\ny\n```")
+	if strings.Count(html, "md-codeline-hl") != 2 {
+		t.Fatalf("Expected the range to be clamped to the 2 lines, got %s", html)
+	}
+	_, err := ToHtml("```go {3-200000000}\nx\ny\n```")
+	if err == nil {
+		t.Fatal("Expected an error for a range past the last line")
+	}
+}
+
+func TestTable(t *testing.T) {
+	html := render(t, "| Name | Code | Count |\n|:---|:---:|---:|\n| **go** | `a|b` | 3 |\n| | \\| | 4\n")
+	if strings.Count(html, `<th class="md-th"`) != 3 {
+		t.Fatalf("Expected 3 header cells, got %s", html)
+	}
+	if strings.Count(html, `<td class="md-td"`) != 6 {
+		t.Fatalf("Expected 6 body cells, got %s", html)
+	}
+	if !strings.Contains(html, `<td class="md-td" style="text-align: center;"><code class="md-code">a|b</code></td>`) {
+		t.Fatalf("Expected centered code cell, got %s", html)
+	}
+	if !strings.Contains(html, `<td class="md-td" style="text-align: left;"><strong class="md-strong">go</strong></td>`) {
+		t.Fatalf("Expected left aligned bold cell, got %s", html)
+	}
+	if !strings.Contains(html, `<td class="md-td" style="text-align: right;">4</td>`) {
+		t.Fatalf("Expected right aligned cell without trailing pipe, got %s", html)
+	}
+}
+
+func TestTableWrongColumnCount(t *testing.T) {
+	_, err := ToHtml("| a | b |\n|---|---|\n| 1 | 2 |\n| 1 | 2 | 3 |\n")
+	if err == nil || !strings.Contains(err.Error(), "row 2 has 3 columns, expected 2") {
+		t.Fatalf("Expected a column count error, got %v", err)
+	}
+	_, err = ToHtml("| a | b |\n|---|\n")
+	if err == nil || !strings.Contains(err.Error(), "delimiter row") {
+		t.Fatalf("Expected a delimiter mismatch error, got %v", err)
+	}
+}
+
+func TestTableWithoutDelimiterIsText(t *testing.T) {
+	html := render(t, "| not a table |")
+	if strings.Contains(html, "<table") {
+		t.Fatalf("Expected a paragraph, got %s", html)
+	}
+}
+
+func TestNestedLists(t *testing.T) {
+	html := render(t, "- one\n  - nested\n    1. deep\n- two\n")
+	if strings.Count(html, `<ul class="md-ul">`) != 2 {
+		t.Fatalf("Expected 2 unordered lists, got %s", html)
+	}
+	if strings.Count(html, `<ol class="md-ol">`) != 1 {
+		t.Fatalf("Expected a nested ordered list, got %s", html)
+	}
+	one := strings.Index(html, "one")
+	nested := strings.Index(html, "nested")
+	deep := strings.Index(html, "deep")
+	two := strings.Index(html, "two")
+	if !(one < nested && nested < deep && deep < two) {
+		t.Fatalf("Expected the items in order, got %s", html)
+	}
+	// The nested list is inside the first item.
+	if strings.Count(html[:two], "</ul>") != 1 {
+		t.Fatalf("Expected the nested list to close before the second item, got %s", html)
+	}
+}
+
+func TestOrderedListStart(t *testing.T) {
+	html := render(t, "3. three\n4. four\n")
+	if !strings.Contains(html, `<ol class="md-ol" start="3">`) {
+		t.Fatalf("Expected the list to start at 3, got %s", html)
+	}
+	html = render(t, "1) one\n2) two\n")
+	if strings.Contains(html, "start=") {
+		t.Fatalf("Lists starting at 1 should not have a start, got %s", html)
+	}
+	html = render(t, "3x not a list")
+	if strings.Contains(html, "<ol") {
+		t.Fatalf("Expected a paragraph, got %s", html)
+	}
+}
+
+func TestTaskList(t *testing.T) {
+	html := render(t, "- [ ] todo\n- [x] done\n- [link](/x)\n")
+	if strings.Count(html, `<input class="md-checkbox" type="checkbox" disabled />`) != 1 {
+		t.Fatalf("Expected an unchecked box, got %s", html)
+	}
+	if strings.Count(html, `<input class="md-checkbox" type="checkbox" disabled checked />`) != 1 {
+		t.Fatalf("Expected a checked box, got %s", html)
+	}
+	if strings.Count(html, "md-task") != 2 {
+		t.Fatalf("Expected 2 task items, got %s", html)
+	}
+}
+
+func TestMultiParagraphListItem(t *testing.T) {
+	html := render(t, "- first line\n  continued\n\n  second paragraph\n- next\n\nAfter")
+	if !strings.Contains(html, "<p class=\"md-li-p\">first line\ncontinued</p><p class=\"md-li-p\">second paragraph</p>") {
+		t.Fatalf("Expected two paragraphs in the first item, got %s", html)
+	}
+	if strings.Count(html, "<li") != 2 {
+		t.Fatalf("Expected 2 items, got %s", html)
+	}
+	if !strings.Contains(html, `<p class="md-p">`) || strings.Index(html, "After") < strings.Index(html, "</ul>") {
+		t.Fatalf("Expected a paragraph after the list, got %s", html)
+	}
+}
+
+func TestMixedListTypes(t *testing.T) {
+	html := render(t, "- a\n1. b\n")
+	if !strings.Contains(html, "<ul") || !strings.Contains(html, "<ol") {
+		t.Fatalf("Expected two lists, got %s", html)
+	}
+}
+
+func TestHeadingIds(t *testing.T) {
+	html := render(t, "# Hello, *World*!\n## Intro\n## Intro\n## Intro-1\n")
+	if !strings.Contains(html, `<h1 class="md-h1 md-heading" id="hello-world">`) {
+		t.Fatalf("Expected a slug id, got %s", html)
+	}
+	if !strings.Contains(html, `<a class="md-anchor" href="#hello-world"`) {
+		t.Fatalf("Expected a permalink, got %s", html)
+	}
+	for _, id := range []string{`id="intro"`, `id="intro-1"`, `id="intro-1-1"`} {
+		if strings.Count(html, id) != 1 {
+			t.Fatalf("Expected exactly one %s, got %s", id, html)
+		}
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	toc, err := TableOfContents("# A\n## B\n### C\n## D\n# E\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toc) != 2 || toc[0].Text != "A" || toc[1].Text != "E" {
+		t.Fatalf("Expected 2 top level headings, got %v", toc)
+	}
+	if len(toc[0].Children) != 2 || toc[0].Children[1].Id != "d" {
+		t.Fatalf("Expected B and D under A, got %v", toc[0].Children)
+	}
+	if len(toc[0].Children[0].Children) != 1 || toc[0].Children[0].Children[0].Level != 3 {
+		t.Fatalf("Expected C under B, got %v", toc[0].Children[0])
+	}
+}
+
+func TestTocMarker(t *testing.T) {
+	html := render(t, "[[toc]]\n# First\nText\n\n## Second\n")
+	nav := strings.Index(html, `<nav class="md-toc">`)
+	if nav < 0 || nav > strings.Index(html, "<h1") {
+		t.Fatalf("Expected the table of contents before the first heading, got %s", html)
+	}
+	if !strings.Contains(html, `<a class="md-toc-a" href="#second">Second</a>`) {
+		t.Fatalf("Expected later headings in the table of contents, got %s", html)
+	}
+}
+
+func TestReferenceLinks(t *testing.T) {
+	html := render(t, "See [the docs][Docs] and [Docs][] or ![logo][img].\n\n[docs]: https://example.com \"The docs\"\n[img]: /logo.png\n")
+	if strings.Count(html, `<a class="md-a" href="https://example.com" title="The docs" target="_blank" rel="noopener noreferrer">`) != 2 {
+		t.Fatalf("Expected 2 reference links, got %s", html)
+	}
+	if !strings.Contains(html, `src="/logo.png"`) {
+		t.Fatalf("Expected a reference image, got %s", html)
+	}
+	if strings.Contains(html, "[docs]:") {
+		t.Fatalf("Definitions should not be rendered, got %s", html)
+	}
+}
+
+func TestFootnotes(t *testing.T) {
+	html := render(t, "First[^b] and second[^a], again[^b].\n\n[^a]: Note *a*.\n[^b]: Note b.\n")
+	if !strings.Contains(html, `<sup class="md-footnote-ref" id="fnref-1"><a href="#fn-1">1</a></sup>`) {
+		t.Fatalf("Expected footnotes to be numbered by first reference, got %s", html)
+	}
+	if !strings.Contains(html, `id="fnref-1-2"`) {
+		t.Fatalf("Expected a second reference id, got %s", html)
+	}
+	section := html[strings.Index(html, `<section class="md-footnotes">`):]
+	if strings.Index(section, "Note b.") > strings.Index(section, "Note <em") {
+		t.Fatalf("Expected footnotes in reference order, got %s", section)
+	}
+	if strings.Count(section, `class="md-footnote-back"`) != 3 {
+		t.Fatalf("Expected a back-link for each reference, got %s", section)
+	}
+}
+
+func TestUndefinedReference(t *testing.T) {
+	_, err := ToHtml("Fine.\n\nA [broken][nope] link")
+	var refErr *ReferenceError
+	if !errors.As(err, &refErr) {
+		t.Fatalf("Expected a reference error, got %v", err)
+	}
+	if refErr.Line != 3 || refErr.Column != 11 || refErr.Label != "nope" {
+		t.Fatalf("Expected the reference on line 3 column 11, got %+v", refErr)
+	}
+	_, err = ToHtml("Missing[^1]")
+	if !errors.As(err, &refErr) || !refErr.Footnote {
+		t.Fatalf("Expected a footnote error, got %v", err)
+	}
+}
+
+func TestMath(t *testing.T) {
+	defer func() { RenderMath = nil }()
+	RenderMath = func(tex string, display bool) (template.HTML, error) {
+		if tex == `\bad` {
+			return "", errors.New("Undefined control sequence")
+		}
+		return template.HTML(fmt.Sprintf("<svg>%s %t</svg>", tex, display)), nil
+	}
+	html := render(t, "Area $\\pi r^2$ costs $5 and $10.\n\n$$\n\\sum_i x_i\n$$\n")
+	if !strings.Contains(html, `<span class="md-math"><svg>\pi r^2 false</svg></span>`) {
+		t.Fatalf("Expected inline math, got %s", html)
+	}
+	if !strings.Contains(html, "costs $5 and $10.") {
+		t.Fatalf("Expected dollar amounts to stay text, got %s", html)
+	}
+	if !strings.Contains(html, `<div class="md-math md-math-display"><svg>\sum_i x_i true</svg></div>`) {
+		t.Fatalf("Expected display math, got %s", html)
+	}
+	html = render(t, "Broken $\\bad$")
+	if !strings.Contains(html, `<span class="md-math-error-msg">Undefined control sequence</span><code class="md-code">\bad</code>`) {
+		t.Fatalf("Expected an error box, got %s", html)
+	}
+}
+
+func TestMathWithoutRenderer(t *testing.T) {
+	html := render(t, "$a<b$")
+	if !strings.Contains(html, `<span class="md-math"><code class="md-code">a&lt;b</code></span>`) {
+		t.Fatalf("Expected the source as code, got %s", html)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := ToHtml("# Title\n\nbad \f here\n")
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+	if perr.Line != 3 || perr.Column != 5 || perr.Rule != "p" || perr.Snippet != "bad \f here" {
+		t.Fatalf("Expected the error on line 3 column 5, got %+v", perr)
+	}
+	_, err = ToHtml("| a | b |\n|---|\n")
+	if !errors.As(err, &perr) || perr.Rule != "table" || !strings.Contains(perr.Message, "delimiter row has 1") {
+		t.Fatalf("Expected a table error, got %v", err)
+	}
+	_, err = ToHtml("Intro\n\n| a | b |\n|---|---|\n| 1 | 2 |\n| 1 | 2 | 3 |\n")
+	if !errors.As(err, &perr) || perr.Rule != "table" || perr.Line != 6 || perr.Column != 1 || perr.Snippet != "| 1 | 2 | 3 |" {
+		t.Fatalf("Expected a table error on line 6, got %+v", perr)
+	}
+	var refErr *ReferenceError
+	_, err = ToHtml("see [x][nope]\n")
+	if !errors.As(err, &perr) || !errors.As(err, &refErr) || perr.Column != 8 {
+		t.Fatalf("Expected a reference error on column 8, got %v", err)
+	}
+}
+
+func TestLenient(t *testing.T) {
+	html, err := ToHtml("# Title\n\nbad <\f> here\n\nafter *em*\n", Lenient)
+	var errs ParseErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Line != 3 {
+		t.Fatalf("Expected one error on line 3, got %v", err)
+	}
+	if !strings.Contains(string(html), "<pre class=\"md-unparsed\">bad &lt;\f&gt; here\n</pre>") {
+		t.Fatalf("Expected the block as escaped text, got %s", html)
+	}
+	if !strings.Contains(string(html), `id="title"`) || !strings.Contains(string(html), `<em class="md-em">em</em>`) {
+		t.Fatalf("Expected the rest of the document, got %s", html)
+	}
+	html, err = ToHtml("ok\n", Lenient)
+	if err != nil || !strings.Contains(string(html), "ok") {
+		t.Fatalf("Expected no errors, got %v", err)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	html, err := ToHtml("")
+	if err != nil || html != "" {
+		t.Fatalf("Expected an empty document, got %q %v", html, err)
+	}
+}
+
+func TestBlockquote(t *testing.T) {
+	html := render(t, "> first\n> second\n")
+	if !strings.Contains(html, "<p class=\"md-p\"> first\nsecond</p>") {
+		t.Fatalf("Expected the markers to be removed, got %s", html)
+	}
+}
+
+func TestCallout(t *testing.T) {
+	html := render(t, "> [!WARNING]\n> Be **careful**\n")
+	if !strings.Contains(html, `<aside class="md-callout md-callout-warning" role="note">`) ||
+		!strings.Contains(html, `<p class="md-callout-title">Warning</p>`) {
+		t.Fatalf("Expected a warning callout, got %s", html)
+	}
+	if !strings.Contains(html, `<p class="md-p">Be <strong class="md-strong">careful</strong></p>`) {
+		t.Fatalf("Expected the callout content, got %s", html)
+	}
+	html = render(t, "> [!tip] Inline\n")
+	if !strings.Contains(html, "md-callout-tip") || !strings.Contains(html, "Inline") {
+		t.Fatalf("Expected a tip callout, got %s", html)
+	}
+}
+
+func TestShortcode(t *testing.T) {
+	defer delete(shortcodes, "card")
+	RegisterShortcode("card", func(args map[string]string) (template.HTML, error) {
+		if args["id"] == "" {
+			return "", errors.New("Missing id")
+		}
+		return template.HTML(fmt.Sprintf(`<div class="card">%s %s</div>`, args["id"], args["name"])), nil
+	})
+	html := render(t, "{{< card id=12 name=\"a \\\"b\\\"\" >}}\n\nInline {{<card id='3'>}} card\n")
+	if !strings.Contains(html, "<div class=\"card\">12 a \"b\"</div>") {
+		t.Fatalf("Expected a block shortcode, got %s", html)
+	}
+	if strings.Contains(html, "<p class=\"md-p\"><div") {
+		t.Fatalf("Expected the block shortcode not to be in a paragraph, got %s", html)
+	}
+	if !strings.Contains(html, `<p class="md-p">Inline <div class="card">3 </div> card</p>`) {
+		t.Fatalf("Expected an inline shortcode, got %s", html)
+	}
+	var perr *ParseError
+	_, err := ToHtml("{{< card >}}")
+	if !errors.As(err, &perr) || !strings.Contains(perr.Message, "Missing id") {
+		t.Fatalf("Expected the shortcode error, got %v", err)
+	}
+	_, err = ToHtml("{{< unknown >}}")
+	if err == nil || !strings.Contains(err.Error(), "Unknown shortcode 'unknown'") {
+		t.Fatalf("Expected an unknown shortcode error, got %v", err)
+	}
+}
+
+func TestResponsiveImage(t *testing.T) {
+	defer func() { ResolveImage = nil }()
+	ResolveImage = func(src string) *ImageInfo {
+		if src != "/asset/cat.png" {
+			return nil
+		}
+		return &ImageInfo{
+			Width:  1600,
+			Height: 900,
+			Srcset: []string{"/asset/cat.png?w=640 640w", "/asset/cat.png 1600w"},
+			Sizes:  "100vw",
+		}
+	}
+	html := render(t, "A ![cat](/asset/cat.png) and ![dog](https://example.com/dog.png){10,20}")
+	if !strings.Contains(html, `srcset="/asset/cat.png?w=640 640w, /asset/cat.png 1600w"`) ||
+		!strings.Contains(html, `sizes="100vw"`) {
+		t.Fatalf("Expected the variants of the asset, got %s", html)
+	}
+	if !strings.Contains(html, `height="900"`) || !strings.Contains(html, `width="1600"`) {
+		t.Fatalf("Expected the intrinsic size, got %s", html)
+	}
+	if strings.Count(html, `loading="lazy"`) != 2 || strings.Count(html, `decoding="async"`) != 2 {
+		t.Fatalf("Expected all images to load lazily, got %s", html)
+	}
+	if strings.Count(html, "srcset") != 1 || !strings.Contains(html, `height="10"`) {
+		t.Fatalf("Expected the external image as is, got %s", html)
+	}
+}
+
+func TestFigure(t *testing.T) {
+	html := render(t, "![A cat](/cat.png \"Our cat\")\n\nInline ![dog](/dog.png 'Title') image\n")
+	if !strings.Contains(html, `<figure class="md-figure">`) ||
+		!strings.Contains(html, `<figcaption class="md-figcaption">Our cat</figcaption>`) {
+		t.Fatalf("Expected a figure, got %s", html)
+	}
+	if strings.Count(html, "<figure") != 1 || !strings.Contains(html, `title="Title"`) {
+		t.Fatalf("Expected the inline image to keep its title, got %s", html)
+	}
+}
+
+func TestLinkDestination(t *testing.T) {
+	html := render(t, "[a](/my page.md \"Title\") [b](/x )")
+	if !strings.Contains(html, `href="/my%20page.md" title="Title"`) || !strings.Contains(html, `href="/x"`) {
+		t.Fatalf("Expected the href and title, got %s", html)
+	}
+}
+
+// A document of about 1000 words with the usual markup, to catch grammar
+// changes that slow down rendering.
+func benchmarkDocument() string {
+	paragraph := "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor " +
+		"incididunt ut labore et *dolore magna* aliqua. Ut enim ad minim veniam, quis **nostrud " +
+		"exercitation** ullamco laboris nisi ut `aliquip` ex ea commodo consequat. Duis aute irure " +
+		"dolor in [reprehenderit](https://example.com) in voluptate velit esse cillum ~~dolore~~ eu " +
+		"fugiat nulla pariatur.\n\n"
+	s := new(strings.Builder)
+	s.WriteString("# Benchmark\n\n")
+	for i := 0; i < 16; i++ {
+		s.WriteString(paragraph)
+		if i%4 == 0 {
+			s.WriteString("- First item\n- Second *item*\n\n")
+		}
+	}
+	return s.String()
+}
+
+func BenchmarkToHtml(b *testing.B) {
+	md := benchmarkDocument()
+	for i := 0; i < b.N; i++ {
+		if _, err := ToHtml(md); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
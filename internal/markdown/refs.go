@@ -0,0 +1,165 @@
+package markdown
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/samuellando/gositter"
+)
+
+// Returned when a link, image or footnote refers to a label that is not
+// defined anywhere in the document.
+type ReferenceError struct {
+	Label    string
+	Footnote bool
+	Line     int
+	Column   int
+	offset   int
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("%s on line %d, column %d", e.message(), e.Line, e.Column)
+}
+
+func (e *ReferenceError) message() string {
+	if e.Footnote {
+		return fmt.Sprintf("Undefined footnote '[^%s]'", e.Label)
+	}
+	return fmt.Sprintf("Undefined reference '[%s]'", e.Label)
+}
+
+// A link definition, [label]: href "title"
+type linkdef struct {
+	Href  string
+	Title string
+}
+
+type footnote struct {
+	Number int
+	Html   template.HTML
+	// The ids of the references to the footnote, for the back-links.
+	Backrefs []string
+	def      gositter.SyntaxTree
+}
+
+// Collects the link and footnote definitions, so that they can be used before
+// they are defined. The first definition of a label is used.
+func (r *renderer) collectDefinitions(tree gositter.SyntaxTree) {
+	for _, def := range tree.Find("linkdef") {
+		label := normalizeLabel(def.Find("label")[0].Value())
+		if _, ok := r.links[label]; ok {
+			continue
+		}
+		ld := linkdef{Href: def.Find("url")[0].Value()}
+		if titles := def.Find("title"); len(titles) > 0 {
+			ld.Title = unquoteTitle(titles[0].Value())
+		}
+		ld.Href = strings.TrimSuffix(strings.TrimPrefix(ld.Href, "<"), ">")
+		r.links[label] = ld
+	}
+	for _, def := range tree.Find("footnotedef") {
+		label := normalizeLabel(def.Find("label")[0].Value())
+		if _, ok := r.footnoteDefs[label]; !ok {
+			r.footnoteDefs[label] = def
+		}
+	}
+}
+
+// Resolves the reference of a link or image, ref is the [label] node.
+// An empty label uses the text of the link instead.
+func (r *renderer) resolve(ref gositter.SyntaxTree, text string) (linkdef, error) {
+	label := text
+	if labels := ref.Find("reflabel"); len(labels) > 0 {
+		label = labels[0].Value()
+	}
+	ld, ok := r.links[normalizeLabel(label)]
+	if !ok {
+		line, column := r.position(ref)
+		return ld, &ReferenceError{Label: label, Line: line, Column: column, offset: r.offsets[ref]}
+	}
+	return ld, nil
+}
+
+// Numbers the footnote by the order of its first reference, and returns the
+// data for the reference.
+func (r *renderer) referenceFootnote(t gositter.SyntaxTree) (footnoteref, error) {
+	raw := t.Find("label")[0].Value()
+	label := normalizeLabel(raw)
+	fn, ok := r.footnotes[label]
+	if !ok {
+		def, defined := r.footnoteDefs[label]
+		if !defined {
+			line, column := r.position(t)
+			return footnoteref{}, &ReferenceError{Label: raw, Footnote: true, Line: line, Column: column, offset: r.offsets[t]}
+		}
+		fn = &footnote{Number: len(r.footnoteOrder) + 1, def: def}
+		r.footnotes[label] = fn
+		r.footnoteOrder = append(r.footnoteOrder, fn)
+	}
+	id := fmt.Sprintf("fnref-%d", fn.Number)
+	if len(fn.Backrefs) > 0 {
+		id = fmt.Sprintf("%s-%d", id, len(fn.Backrefs)+1)
+	}
+	fn.Backrefs = append(fn.Backrefs, id)
+	return footnoteref{Id: id, Number: fn.Number}, nil
+}
+
+type footnoteref struct {
+	Id     string
+	Number int
+}
+
+// Renders the referenced footnotes, footnotes can reference other footnotes
+// so the list can grow while rendering.
+func (r *renderer) renderFootnotes() (template.HTML, error) {
+	if len(r.footnoteOrder) == 0 {
+		return "", nil
+	}
+	for i := 0; i < len(r.footnoteOrder); i++ {
+		fn := r.footnoteOrder[i]
+		html, err := r.parseTree(fn.def.Find("footnotetext")[0])
+		if err != nil {
+			perr := r.blockError(fn.def, err)
+			if !r.options.Lenient {
+				return "", perr
+			}
+			r.errors = append(r.errors, perr)
+			html = template.HTML(template.HTMLEscapeString(fn.def.Find("footnotetext")[0].Value()))
+		}
+		fn.Html = html
+	}
+	s := new(strings.Builder)
+	err := COMPONENTS.ExecuteTemplate(s, "footnotes", r.footnoteOrder)
+	return template.HTML(s.String()), err
+}
+
+// Labels are case insensitive, and whitespace is collapsed.
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// Returns the line and column of the node in the source, both starting at 1.
+func (r *renderer) position(t gositter.SyntaxTree) (int, int) {
+	if r.offsets == nil {
+		r.offsets = make(map[gositter.SyntaxTree]int)
+		var walk func(t gositter.SyntaxTree, offset int) int
+		walk = func(t gositter.SyntaxTree, offset int) int {
+			r.offsets[t] = offset
+			nodes := t.Nodes()
+			if len(nodes) == 0 {
+				return offset + len(t.Value())
+			}
+			for _, n := range nodes {
+				offset = walk(n, offset)
+			}
+			return offset
+		}
+		for _, seg := range r.segments {
+			if seg.tree != nil {
+				walk(seg.tree, seg.offset)
+			}
+		}
+	}
+	return lineColumn(r.source, r.offsets[t])
+}
@@ -0,0 +1,130 @@
+package markdown
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The inline html elements allowed in markdown, with their allowed
+// attributes. Any other tag is rendered as text.
+var AllowedElements = map[string][]string{
+	"abbr":  {"title"},
+	"br":    nil,
+	"del":   nil,
+	"ins":   nil,
+	"kbd":   nil,
+	"mark":  nil,
+	"s":     nil,
+	"small": nil,
+	"span":  {"title"},
+	"sub":   nil,
+	"sup":   nil,
+	"u":     nil,
+	"wbr":   nil,
+}
+
+// The url schemes allowed in links and images, urls without a scheme are
+// always allowed.
+var AllowedSchemes = []string{"http", "https", "mailto", "tel"}
+
+var voidElements = []string{"br", "wbr"}
+
+var (
+	tagPattern       = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9]*)((?:[ \t]+[^\s=/>]+(?:[ \t]*=[ \t]*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?)*)[ \t]*/?>$`)
+	attributePattern = regexp.MustCompile(`([^\s=/>]+)(?:[ \t]*=[ \t]*("[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?`)
+)
+
+// Renders an inline html tag, the tag is escaped unless its element is in
+// AllowedElements. Attributes that are not allowed are dropped.
+//
+// Closing tags are escaped unless they close an element opened in the same
+// tree, so they can not close the elements of the components, see parseTree.
+func (r *renderer) sanitizeTag(tag string) template.HTML {
+	m := tagPattern.FindStringSubmatch(tag)
+	if m == nil {
+		return template.HTML(template.HTMLEscapeString(tag))
+	}
+	closing, name := m[1] == "/", strings.ToLower(m[2])
+	allowed, ok := AllowedElements[name]
+	if !ok {
+		return template.HTML(template.HTMLEscapeString(tag))
+	}
+	void := slices.Contains(voidElements, name)
+	if closing {
+		if void {
+			return ""
+		}
+		i := len(r.openTags) - 1
+		for i >= 0 && r.openTags[i] != name {
+			i--
+		}
+		if i < 0 {
+			return template.HTML(template.HTMLEscapeString(tag))
+		}
+		// The elements opened inside it are closed with it.
+		return template.HTML(r.closeTags(i))
+	}
+	if !void {
+		r.openTags = append(r.openTags, name)
+	}
+	s := new(strings.Builder)
+	s.WriteString("<" + name)
+	for _, attr := range attributePattern.FindAllStringSubmatch(m[3], -1) {
+		key, value := strings.ToLower(attr[1]), attr[2]
+		if !slices.Contains(allowed, key) {
+			continue
+		}
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+			value = value[1 : len(value)-1]
+		}
+		fmt.Fprintf(s, ` %s="%s"`, key, template.HTMLEscapeString(value))
+	}
+	s.WriteString(">")
+	return template.HTML(s.String())
+}
+
+// Closes the open elements from the ith one, and returns their closing tags
+// innermost first.
+func (r *renderer) closeTags(i int) string {
+	s := new(strings.Builder)
+	for j := len(r.openTags) - 1; j >= i; j-- {
+		fmt.Fprintf(s, "</%s>", r.openTags[j])
+	}
+	r.openTags = r.openTags[:i]
+	return s.String()
+}
+
+// Reports if the url is relative or has an allowed scheme, ie not
+// "javascript:".
+func safeUrl(u string) bool {
+	// Browsers ignore whitespace and control characters in the scheme.
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, u)
+	i := strings.IndexAny(cleaned, ":/?#")
+	if i < 0 || cleaned[i] != ':' {
+		return true
+	}
+	return slices.Contains(AllowedSchemes, strings.ToLower(cleaned[:i]))
+}
+
+// Returns the url if it is safe, otherwise a link to nowhere.
+func sanitizeUrl(u string) string {
+	if safeUrl(u) {
+		return u
+	}
+	return "#"
+}
+
+// Reports if the url points to another site, ie "https://example.com".
+func externalUrl(u string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	return err == nil && parsed.Host != ""
+}
@@ -0,0 +1,149 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"samuellando.com/internal/markdown/latex"
+)
+
+// The elements and attributes rendered by the components.
+var (
+	componentElements = []string{"a", "aside", "blockquote", "code", "del", "div", "em", "figcaption", "figure",
+		"h1", "h2", "h3", "h4", "h5", "h6", "img", "input", "li", "nav", "ol", "p", "pre", "section", "span",
+		"strong", "sup", "table", "tbody", "td", "th", "thead", "tr", "ul"}
+	componentAttributes = []string{"alt", "aria-label", "checked", "class", "decoding", "disabled", "height",
+		"href", "id", "loading", "rel", "role", "sizes", "src", "srcset", "start", "style", "target", "title", "type",
+		"width"}
+	// The elements and attributes of the svg of rendered math.
+	mathElements   = []string{"svg", "g", "path"}
+	mathAttributes = []string{"version", "width", "height", "viewbox", "xmlns", "xmlns:xlink", "d", "fill",
+		"fill-rule", "stroke", "stroke-width", "stroke-linecap", "stroke-linejoin", "transform"}
+	outputTag       = regexp.MustCompile(`<(/?)([A-Za-z][A-Za-z0-9-]*)([^>]*)>`)
+	outputAttribute = regexp.MustCompile(`([^\s=/>"']+)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s>]+))?`)
+	rawTag          = regexp.MustCompile(`<[A-Za-z/!?]`)
+)
+
+// Checks that every tag in the html is rendered by a component or allowed,
+// with safe attributes.
+func checkSafe(s string) error {
+	for _, m := range outputTag.FindAllStringSubmatch(s, -1) {
+		name := strings.ToLower(m[2])
+		_, allowed := AllowedElements[name]
+		math := slices.Contains(mathElements, name)
+		if !allowed && !math && !slices.Contains(componentElements, name) {
+			return fmt.Errorf("Unexpected element %s", m[0])
+		}
+		attributes := componentAttributes
+		if math {
+			attributes = mathAttributes
+		}
+		for _, attr := range outputAttribute.FindAllStringSubmatch(m[3], -1) {
+			key := strings.ToLower(attr[1])
+			if !slices.Contains(attributes, key) {
+				return fmt.Errorf("Unexpected attribute %s in %s", key, m[0])
+			}
+			value := strings.Trim(attr[2], `"'`)
+			if (key == "href" || key == "src") && !safeUrl(html.UnescapeString(value)) {
+				return fmt.Errorf("Unsafe url in %s", m[0])
+			}
+		}
+	}
+	if raw := rawTag.FindString(outputTag.ReplaceAllString(s, "")); raw != "" {
+		return fmt.Errorf("Unescaped html %s", raw)
+	}
+	return nil
+}
+
+func TestEscapesText(t *testing.T) {
+	html := render(t, "A <script>alert(1)</script> and [<img src=x onerror=alert(1)>](/x)\n\n# <b>Title</b>\n")
+	if strings.Contains(html, "<script") || strings.Contains(html, "<img src=x") || strings.Contains(html, "<b>") {
+		t.Fatalf("Expected the html to be escaped, got %s", html)
+	}
+	if !strings.Contains(html, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Fatalf("Expected the script as text, got %s", html)
+	}
+	if err := checkSafe(html); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAllowedElements(t *testing.T) {
+	html := render(t, `H<sub>2</sub>O, <kbd onclick="x()">Ctrl</kbd>, <abbr title="Hyper &quot;Text&quot;" style="x">HTML</abbr><br/>`)
+	for _, expected := range []string{"<sub>2</sub>", "<kbd>Ctrl</kbd>", `<abbr title="Hyper &amp;quot;Text&amp;quot;">HTML</abbr>`, "<br>"} {
+		if !strings.Contains(html, expected) {
+			t.Fatalf("Expected %s, got %s", expected, html)
+		}
+	}
+}
+
+func TestUnopenedClosingTags(t *testing.T) {
+	html := render(t, "> [!NOTE]\n> a</span></div> <u>b *c</u>* d\n")
+	if strings.Contains(html, "</span></div>") || !strings.Contains(html, "&lt;/span&gt;&lt;/div&gt;") {
+		t.Fatalf("Expected the closing tags without openers to be escaped, got %s", html)
+	}
+	if !strings.Contains(html, "<u>b <em class=\"md-em\">c&lt;/u&gt;</em> d</u>") {
+		t.Fatalf("Expected the tags to be closed where they were opened, got %s", html)
+	}
+	if err := checkSafe(html); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSafeUrls(t *testing.T) {
+	for _, u := range []string{"javascript:alert(1)", "JaVaScRiPt:alert(1)", "java\tscript:alert(1)", " javascript:x", "data:text/html,x", "vbscript:x"} {
+		if safeUrl(u) {
+			t.Fatalf("Expected '%s' to be unsafe", u)
+		}
+	}
+	for _, u := range []string{"/asset/a.png", "page.md", "#section", "https://example.com", "mailto:a@b.c", "?q=a:b", "a/b:c"} {
+		if !safeUrl(u) {
+			t.Fatalf("Expected '%s' to be safe", u)
+		}
+	}
+	html := render(t, "[x](javascript:alert(1)) ![y](javascript:alert(1)) [z][ref]\n\n[ref]: javascript:alert(1)\n")
+	if strings.Contains(strings.ToLower(html), "javascript") {
+		t.Fatalf("Expected the urls to be removed, got %s", html)
+	}
+}
+
+func TestExternalLinks(t *testing.T) {
+	html := render(t, "[in](/about) [out](https://example.com) [proto](//example.com)")
+	if !strings.Contains(html, `<a class="md-a" href="/about">`) {
+		t.Fatalf("Expected the internal link as is, got %s", html)
+	}
+	if strings.Count(html, `target="_blank" rel="noopener noreferrer"`) != 2 {
+		t.Fatalf("Expected the external links to open in a new tab, got %s", html)
+	}
+}
+
+func FuzzToHtml(f *testing.F) {
+	for _, seed := range []string{
+		"# Title\n\nSome *text* and a [link](https://example.com \"t\").",
+		"<script>alert(1)</script>",
+		"[<img src=x onerror=alert(1)>](javascript:alert(1))",
+		"![a\" onerror=\"alert(1)](/x.png \"c\")",
+		"<abbr title='x' onmouseover=alert(1)>a</abbr><svg onload=alert(1)>",
+		"| <b> | b |\n|---|---|\n| [x](javascript:x) | <i> |\n",
+		"- <iframe>\n- [ ] `<code>`\n\n```html\n<script>\n```\n",
+		"> [!NOTE]\n> <style>x</style>\n\n[^1]: <a href=x>\n\nText[^1]",
+		"[[toc]]\n\n## <h1>x</h1>\n",
+		"> [!TIP]\n> a</span></div></aside>\n\n<span>*b</span>*\n",
+		"$\\frac{a}{b}$ and $$\\sum_{i=0}^n x^i$$\n\n$$\n\\sqrt{<svg onload=x>}\n$$\n",
+	} {
+		f.Add(seed)
+	}
+	defer func(render func(string, bool) (template.HTML, error)) { RenderMath = render }(RenderMath)
+	RenderMath = latex.Renderer(nil)
+	f.Fuzz(func(t *testing.T, md string) {
+		html, _ := ToHtml(md, Lenient)
+		if err := checkSafe(string(html)); err != nil {
+			t.Fatalf("%s\n%q\n%s", err, md, html)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package markdown
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/samuellando/gositter"
+)
+
+// Renders the html of a shortcode, ie {{< project id=123 >}}, from its
+// arguments.
+type Shortcode func(args map[string]string) (template.HTML, error)
+
+var shortcodes = make(map[string]Shortcode)
+
+// Registers the shortcode under the name, replacing any existing one.
+//
+// Shortcodes should be registered before any markdown is rendered.
+func RegisterShortcode(name string, s Shortcode) {
+	shortcodes[name] = s
+}
+
+// The name and arguments of the shortcode.
+func shortcodeArgs(t gositter.SyntaxTree) (string, map[string]string, error) {
+	name := t.Find("shortcodename")[0].Value()
+	args := make(map[string]string)
+	for _, arg := range t.Find("shortcodearg") {
+		value := arg.Find("argvalue")[0].Value()
+		switch value[0] {
+		case '"':
+			var err error
+			value, err = strconv.Unquote(value)
+			if err != nil {
+				return name, nil, fmt.Errorf("Invalid argument for shortcode '%s': %s", name, arg.Value())
+			}
+		case '\'':
+			value = value[1 : len(value)-1]
+		}
+		args[arg.Find("argname")[0].Value()] = value
+	}
+	return name, args, nil
+}
+
+func renderShortcode(t gositter.SyntaxTree) (template.HTML, error) {
+	name, args, err := shortcodeArgs(t)
+	if err != nil {
+		return "", err
+	}
+	s, ok := shortcodes[name]
+	if !ok {
+		return "", fmt.Errorf("Unknown shortcode '%s'", name)
+	}
+	html, err := s(args)
+	if err != nil {
+		return "", fmt.Errorf("Shortcode '%s' failed: %w", name, err)
+	}
+	return html, nil
+}
+
+// A GitHub style callout, a blockquote starting with [!NOTE].
+type callout struct {
+	Kind  string
+	Title string
+	Html  template.HTML
+}
+
+func newCallout(kind string, html template.HTML) callout {
+	kind = strings.ToLower(kind)
+	return callout{
+		Kind:  kind,
+		Title: strings.ToUpper(kind[:1]) + kind[1:],
+		Html:  html,
+	}
+}
@@ -0,0 +1,150 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/samuellando/gositter"
+)
+
+// A heading in a document's table of contents.
+type Heading struct {
+	Level    int
+	Id       string
+	Text     string
+	Children []Heading
+}
+
+// The data given to the header components.
+type heading struct {
+	Id   string
+	Html template.HTML
+}
+
+// Written in place of a [[toc]] marker, and replaced once all the headings
+// in the document are known.
+const tocMarker = "\x00toc\x00"
+
+// Returns the nested outline of the headings in the document.
+//
+// Errors are returned like ToHtml, in lenient mode the outline is returned
+// along with the errors.
+func TableOfContents(md string, opts ...func(*Options)) ([]Heading, error) {
+	rendered, err := Render(md, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(rendered.Errors) > 0 {
+		return rendered.TableOfContents, rendered.Errors
+	}
+	return rendered.TableOfContents, nil
+}
+
+func (r *renderer) parseHeading(tag string, span gositter.SyntaxTree) (heading, error) {
+	inner, err := r.parseTree(span)
+	if err != nil {
+		return heading{}, err
+	}
+	level := int(tag[1] - '0')
+	text := plainText(inner)
+	id := r.uniqueId(slugify(text))
+	r.headings = append(r.headings, Heading{Level: level, Id: id, Text: text})
+	return heading{Id: id, Html: inner}, nil
+}
+
+// Headings with the same slug are numbered in order ie intro, intro-1, intro-2.
+func (r *renderer) uniqueId(slug string) string {
+	n := r.ids[slug]
+	r.ids[slug] = n + 1
+	if n == 0 {
+		return slug
+	}
+	id := fmt.Sprintf("%s-%d", slug, n)
+	// A heading might already use the numbered slug.
+	if _, ok := r.ids[id]; ok {
+		return r.uniqueId(slug)
+	}
+	r.ids[id] = 1
+	return id
+}
+
+// Nests the headings, each heading contains the following headings of a
+// lower level.
+func (r *renderer) toc() []Heading {
+	var build func(headings []Heading) ([]Heading, []Heading)
+	// Consumes headings deeper than the first one as its children.
+	build = func(headings []Heading) ([]Heading, []Heading) {
+		outline := make([]Heading, 0)
+		for len(headings) > 0 {
+			h := headings[0]
+			if len(outline) > 0 && h.Level < outline[0].Level {
+				break
+			}
+			headings = headings[1:]
+			if len(headings) > 0 && headings[0].Level > h.Level {
+				h.Children, headings = build(headings)
+			}
+			outline = append(outline, h)
+		}
+		return outline, headings
+	}
+	outline := make([]Heading, 0)
+	rest := r.headings
+	for len(rest) > 0 {
+		var part []Heading
+		part, rest = build(rest)
+		outline = append(outline, part...)
+	}
+	return outline
+}
+
+// Replaces the [[toc]] markers with the rendered table of contents.
+func (r *renderer) insertToc(html template.HTML) (template.HTML, error) {
+	if !strings.Contains(string(html), tocMarker) {
+		return html, nil
+	}
+	s := new(strings.Builder)
+	err := COMPONENTS.ExecuteTemplate(s, "toc", r.toc())
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(strings.ReplaceAll(string(html), tocMarker, s.String())), nil
+}
+
+var tags = regexp.MustCompile(`<[^>]*>`)
+
+func plainText(h template.HTML) string {
+	text := tags.ReplaceAllString(string(h), "")
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+func slugify(text string) string {
+	if s := Slugify(text); s != "" {
+		return s
+	}
+	return "section"
+}
+
+// Converts text into a url fragment, ie "Hello, World!" becomes "hello-world".
+// Returns an empty string if the text has no letters or digits.
+func Slugify(text string) string {
+	s := new(strings.Builder)
+	dash := false
+	for _, c := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			if dash && s.Len() > 0 {
+				s.WriteRune('-')
+			}
+			dash = false
+			s.WriteRune(c)
+		case unicode.IsSpace(c) || c == '-' || c == '_':
+			dash = true
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,133 @@
+// This package recommends related documents and projects, by their shared
+// tags, the similarity of their text and how recent they are.
+package related
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"samuellando.com/internal/search"
+)
+
+// An item that can be recommended, ie a document or a project.
+type Item struct {
+	Kind  string
+	Id    int64
+	Title string
+	Url   string
+	// When the item was created or last updated, recent items score higher.
+	Date time.Time
+	Tags []string
+	// The text used to compare items, as indexed for search.
+	Text string
+}
+
+// The functions returning the items that can be recommended, ie only the
+// published documents. Sources are set up by the application.
+var Sources []func() ([]Item, error)
+
+// How much each score counts, they add up to 1.
+const (
+	TagWeight     = 0.6
+	TextWeight    = 0.25
+	RecencyWeight = 0.15
+)
+
+// The age at which the recency score of an item is halved.
+const HalfLife = 365 * 24 * time.Hour
+
+// Items that share less than this with the item are not related, however
+// recent they are.
+const minRelevance = 0.05
+
+// Returns the n items of the sources most related to the item, the most
+// related first.
+func Find(item Item, n int) ([]Item, error) {
+	candidates := make([]Item, 0)
+	for _, source := range Sources {
+		items, err := source()
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, items...)
+	}
+	return Rank(item, candidates, n, time.Now()), nil
+}
+
+type scored struct {
+	Item
+	score float64
+}
+
+// Returns the n candidates most related to the item at the time, the most
+// related first.
+//
+// Shared tags are weighted by their rarity among the candidates, so sharing a
+// rare tag counts more than sharing a common one.
+func Rank(item Item, candidates []Item, n int, now time.Time) []Item {
+	weights := tagWeights(candidates)
+	total := 0.0
+	for _, t := range item.Tags {
+		total += weights[t]
+	}
+	vector := search.NewVector(item.Text)
+	results := make([]scored, 0)
+	for _, c := range candidates {
+		if c.Kind == item.Kind && c.Id == item.Id {
+			continue
+		}
+		tags := 0.0
+		if total > 0 {
+			for _, t := range c.Tags {
+				if slices.Contains(item.Tags, t) {
+					tags += weights[t]
+				}
+			}
+			tags /= total
+		}
+		text := vector.Similarity(search.NewVector(c.Text))
+		relevance := TagWeight*tags + TextWeight*text
+		if relevance < minRelevance*(TagWeight+TextWeight) {
+			continue
+		}
+		results = append(results, scored{c, relevance + RecencyWeight*recency(c.Date, now)})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].Date.After(results[j].Date)
+	})
+	items := make([]Item, 0, n)
+	for i := 0; i < len(results) && i < n; i++ {
+		items = append(items, results[i].Item)
+	}
+	return items
+}
+
+// Returns the inverse document frequency of each tag of the items, tags that
+// few items have weigh more.
+func tagWeights(items []Item) map[string]float64 {
+	counts := make(map[string]int)
+	for _, item := range items {
+		for _, t := range item.Tags {
+			counts[t]++
+		}
+	}
+	weights := make(map[string]float64, len(counts))
+	for t, count := range counts {
+		weights[t] = 1 + math.Log(float64(len(items))/float64(count))
+	}
+	return weights
+}
+
+// Returns 1 for items from now, halved every HalfLife.
+func recency(date, now time.Time) float64 {
+	if date.IsZero() {
+		return 0
+	}
+	age := max(now.Sub(date), 0)
+	return math.Pow(0.5, float64(age)/float64(HalfLife))
+}
@@ -0,0 +1,69 @@
+package related
+
+import (
+	"testing"
+	"time"
+)
+
+func ids(items []Item) []int64 {
+	s := make([]int64, len(items))
+	for i, item := range items {
+		s[i] = item.Id
+	}
+	return s
+}
+
+func TestRankRareTags(t *testing.T) {
+	now := time.Now()
+	item := Item{Kind: "document", Id: 1, Tags: []string{"go", "parsers"}, Date: now}
+	candidates := []Item{
+		item,
+		{Kind: "document", Id: 2, Tags: []string{"go"}, Date: now},
+		{Kind: "project", Id: 3, Tags: []string{"parsers"}, Date: now},
+		{Kind: "document", Id: 4, Tags: []string{"go"}, Date: now},
+		{Kind: "project", Id: 5, Tags: []string{"go"}, Date: now},
+		{Kind: "document", Id: 6, Tags: []string{"cooking"}, Date: now},
+	}
+	got := ids(Rank(item, candidates, 10, now))
+	// The rare tag first, and unrelated items are left out.
+	if len(got) != 4 || got[0] != 3 {
+		t.Fatalf("Expected 4 related items starting with 3, got %v", got)
+	}
+	if got := Rank(item, candidates, 2, now); len(got) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(got))
+	}
+}
+
+func TestRankRecencyAndText(t *testing.T) {
+	now := time.Now()
+	item := Item{Kind: "document", Id: 1, Tags: []string{"go"}, Text: "Writing a markdown parser"}
+	candidates := []Item{
+		{Kind: "document", Id: 2, Tags: []string{"go"}, Date: now.Add(-3 * HalfLife)},
+		{Kind: "document", Id: 3, Tags: []string{"go"}, Date: now},
+		{Kind: "project", Id: 4, Text: "A markdown parser", Date: now},
+	}
+	got := ids(Rank(item, candidates, 10, now))
+	if len(got) != 3 || got[0] != 3 || got[1] != 2 || got[2] != 4 {
+		t.Fatalf("Expected [3 2 4], got %v", got)
+	}
+	// The same id of another kind is a different item.
+	item = Item{Kind: "project", Id: 3, Tags: []string{"go"}}
+	if got := ids(Rank(item, candidates, 10, now)); len(got) != 2 || got[0] != 3 {
+		t.Fatalf("Expected the document 3 to be related to the project 3, got %v", got)
+	}
+}
+
+func TestFind(t *testing.T) {
+	defer func() { Sources = nil }()
+	Sources = []func() ([]Item, error){
+		func() ([]Item, error) { return []Item{{Kind: "document", Id: 2, Tags: []string{"go"}}}, nil },
+		func() ([]Item, error) { return []Item{{Kind: "project", Id: 2, Tags: []string{"go"}}}, nil },
+	}
+	items, err := Find(Item{Kind: "document", Id: 1, Tags: []string{"go"}}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected items of both sources, got %v", items)
+	}
+}
@@ -0,0 +1,42 @@
+package search
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// The frequencies of the words of an indexed text, used to compare texts.
+type Vector map[string]float64
+
+// Returns the vector of the words of the text, ignoring case and words shorter
+// than 3 letters.
+func NewVector(text string) Vector {
+	v := make(Vector)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		if len([]rune(word)) >= 3 {
+			v[word]++
+		}
+	}
+	return v
+}
+
+// Returns the cosine similarity of the vectors, from 0 for texts without
+// common words to 1 for texts with the same words.
+func (v Vector) Similarity(o Vector) float64 {
+	var dot, a, b float64
+	for word, n := range v {
+		dot += n * o[word]
+		a += n * n
+	}
+	for _, n := range o {
+		b += n * n
+	}
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(a) * math.Sqrt(b))
+}
@@ -15,6 +15,7 @@ type Asset struct {
 	created time.Time
 	content []byte
 	loaded  bool
+	deleted time.Time
 }
 
 type ProtoAsset struct {
@@ -49,9 +50,10 @@ func (a *Asset) Content() ([]byte, error) {
 	return content, nil
 }
 
+// Moves the asset to the trash, see Store.Restore and Store.Purge.
 func (a *Asset) Delete() error {
 	ctx := context.TODO()
 	queries := data.New(a.db)
-	err := queries.DeleteAsset(ctx, a.id)
+	err := queries.TrashAsset(ctx, a.id)
 	return err
 }
@@ -38,7 +38,18 @@ func (h *Handler) getAsset(w http.ResponseWriter, req *http.Request) {
 			}
 			return
 		}
-		content, err := asset.Content()
+		// A resized variant of an image, ie ?w=640
+		width, err := ParseVariantWidth(req.FormValue("w"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), err), 400)
+			return
+		}
+		var content []byte
+		if width > 0 {
+			content, err = asset.Resized(width)
+		} else {
+			content, err = asset.Content()
+		}
 		if err != nil {
 			http.Error(w, "Unable to load content", 500)
 			return
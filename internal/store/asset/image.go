@@ -0,0 +1,190 @@
+package asset
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "image/gif"
+
+	"samuellando.com/data"
+	"samuellando.com/internal/cache"
+	"samuellando.com/internal/markdown"
+)
+
+// The widths of the resized variants of images, in pixels.
+var VariantWidths = []int{320, 640, 960, 1280, 1920}
+
+// The width images are displayed at in documents.
+const SIZES = "(max-width: 1024px) 100vw, 1024px"
+
+// The resized variants of images, by their name, width and content.
+var VariantCache = cache.NewLRU(64, func(co *cache.CacheOptions) {
+	co.MaxAge = 30 * 24 * time.Hour
+})
+
+// The width, height and format of images, by their name and content hash.
+var DimensionsCache = cache.NewLRU(1024, func(co *cache.CacheOptions) {
+	co.MaxAge = 30 * 24 * time.Hour
+})
+
+type dimensions struct {
+	Width  int
+	Height int
+	Format string
+}
+
+// Returns the width of the variant requested with ?w=, ie "640", or 0 for the
+// original image. Only the VariantWidths can be requested.
+func ParseVariantWidth(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	width, err := strconv.Atoi(s)
+	if err != nil || !slices.Contains(VariantWidths, width) {
+		return 0, fmt.Errorf("Invalid image width '%s'", s)
+	}
+	return width, nil
+}
+
+// Returns the width, height and format ie "png" of an image asset.
+func (a *Asset) Dimensions() (int, int, string, error) {
+	content, err := a.Content()
+	if err != nil {
+		return 0, 0, "", err
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return 0, 0, "", err
+	}
+	return config.Width, config.Height, format, nil
+}
+
+// Returns the image resized to the width, keeping its aspect ratio.
+//
+// Images are never enlarged, and only jpeg and png images are resized, others
+// are returned as is.
+func (a *Asset) Resized(width int) ([]byte, error) {
+	content, err := a.Content()
+	if err != nil {
+		return nil, err
+	}
+	// The content is part of the key, since assets can be replaced.
+	key := fmt.Sprintf("%s?w=%d&crc=%d", a.name, width, crc32.ChecksumIEEE(content))
+	return VariantCache.Get(key, func() ([]byte, error) {
+		img, format, err := image.Decode(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		if img.Bounds().Dx() <= width || (format != "jpeg" && format != "png") {
+			return content, nil
+		}
+		buf := new(bytes.Buffer)
+		resized := resize(img, width)
+		if format == "jpeg" {
+			err = jpeg.Encode(buf, resized, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(buf, resized)
+		}
+		return buf.Bytes(), err
+	})
+}
+
+// Scales the image down to the width, averaging the pixels of each area.
+func resize(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	height := max(1, b.Dy()*width/b.Dx())
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/width)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// Returns the size and variants of images served from /asset/, for use as
+// markdown.ResolveImage.
+func (as Store) ResolveImage(src string) *markdown.ImageInfo {
+	u, err := url.Parse(src)
+	if err != nil || u.IsAbs() || u.RawQuery != "" || !strings.HasPrefix(u.Path, "/asset/") {
+		return nil
+	}
+	name := strings.TrimPrefix(u.Path, "/asset/")
+	width, height, format, err := as.dimensions(name)
+	if err != nil {
+		return nil
+	}
+	info := &markdown.ImageInfo{Width: width, Height: height}
+	if format != "jpeg" && format != "png" {
+		return info
+	}
+	for _, w := range VariantWidths {
+		if w < width {
+			info.Srcset = append(info.Srcset, fmt.Sprintf("/asset/%s?w=%d %dw", url.PathEscape(name), w, w))
+		}
+	}
+	if len(info.Srcset) > 0 {
+		info.Srcset = append(info.Srcset, fmt.Sprintf("/asset/%s %dw", url.PathEscape(name), width))
+		info.Sizes = SIZES
+	}
+	return info
+}
+
+// Returns the width, height and format of the image asset, only loading its
+// content when it changed.
+func (as Store) dimensions(name string) (int, int, string, error) {
+	ctx := context.TODO()
+	queries := data.New(as.db)
+	hash, err := queries.GetAssetHash(ctx, name)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	b, err := DimensionsCache.Get("dimensions:"+name+":"+hash.String, func() ([]byte, error) {
+		a, err := as.GetByName(name)
+		if err != nil {
+			return nil, err
+		}
+		var d dimensions
+		d.Width, d.Height, d.Format, err = a.Dimensions()
+		if err != nil {
+			return nil, err
+		}
+		return encode(d)
+	})
+	if err != nil {
+		return 0, 0, "", err
+	}
+	var d dimensions
+	if err := decode(b, &d); err != nil {
+		return 0, 0, "", err
+	}
+	return d.Width, d.Height, d.Format, nil
+}
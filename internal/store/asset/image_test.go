@@ -0,0 +1,64 @@
+package asset
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func testImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResized(t *testing.T) {
+	a := Asset{name: "red.png", content: testImage(t, 400, 200), loaded: true}
+	width, height, format, err := a.Dimensions()
+	if err != nil || width != 400 || height != 200 || format != "png" {
+		t.Fatalf("Expected a 400x200 png, got %dx%d %s, error: %v", width, height, format, err)
+	}
+	resized, err := a.Resized(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(resized))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 50 {
+		t.Fatalf("Expected a 100x50 image, got %v", img.Bounds())
+	}
+	if r, _, _, a := img.At(50, 25).RGBA(); r != 0xffff || a != 0xffff {
+		t.Fatalf("Expected the color to be kept, got %v", img.At(50, 25))
+	}
+	same, err := a.Resized(800)
+	if err != nil || !bytes.Equal(same, a.content) {
+		t.Fatal("Expected images not to be enlarged")
+	}
+}
+
+func TestParseVariantWidth(t *testing.T) {
+	if width, err := ParseVariantWidth(""); err != nil || width != 0 {
+		t.Fatalf("Expected the original image without a width, got %d, error: %v", width, err)
+	}
+	if width, err := ParseVariantWidth("640"); err != nil || width != 640 {
+		t.Fatalf("Expected the 640 variant, got %d, error: %v", width, err)
+	}
+	for _, s := range []string{"100", "641", "-1", "0", "wide"} {
+		if _, err := ParseVariantWidth(s); err == nil {
+			t.Fatalf("Expected an error for the width '%s'", s)
+		}
+	}
+}
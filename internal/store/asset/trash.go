@@ -0,0 +1,74 @@
+package asset
+
+import (
+	"context"
+	"time"
+
+	"samuellando.com/data"
+	apperrors "samuellando.com/internal/errors"
+	"samuellando.com/internal/store"
+)
+
+// The time the asset was moved to the trash, or the zero time if it is not in
+// the trash.
+func (a Asset) Deleted() time.Time {
+	return a.deleted
+}
+
+// Returns the assets in the trash, the most recently deleted first.
+func (as Store) Trashed() ([]Asset, error) {
+	ctx := context.TODO()
+	queries := data.New(as.db)
+	rows, err := queries.GetDeletedAssets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assets := make([]Asset, len(rows))
+	for i, row := range rows {
+		assets[i] = Asset{
+			db:      as.db,
+			id:      row.ID,
+			name:    row.Name,
+			created: row.Created,
+			loaded:  false,
+			deleted: row.DeletedAt.Time,
+		}
+	}
+	return assets, nil
+}
+
+// Moves the asset with the id out of the trash.
+func (as Store) Restore(id int64) error {
+	ctx := context.TODO()
+	queries := data.New(as.db)
+	n, err := queries.RestoreAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apperrors.CreateNotFoundError("Asset")
+	}
+	return nil
+}
+
+// Permanently deletes the asset with the id, it must be in the trash.
+func (as Store) Purge(id int64) error {
+	ctx := context.TODO()
+	queries := data.New(as.db)
+	n, err := queries.PurgeAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apperrors.CreateNotFoundError("Asset")
+	}
+	return nil
+}
+
+// Permanently deletes the assets moved to the trash before the time, and
+// returns how many were deleted.
+func (as Store) PurgeDeletedBefore(t time.Time) (int64, error) {
+	ctx := context.TODO()
+	queries := data.New(as.db)
+	return queries.PurgeAssetsDeletedBefore(ctx, store.NullTime(t))
+}
@@ -0,0 +1,326 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"samuellando.com/data"
+	"samuellando.com/internal/markdown"
+)
+
+// The directories of an archive, see Store.Export.
+const (
+	ArchiveDocuments = "documents"
+	ArchiveAssets    = "assets"
+)
+
+// What happened to a file of an imported archive.
+type ImportAction string
+
+const (
+	Created ImportAction = "created"
+	Updated ImportAction = "updated"
+	Skipped ImportAction = "skipped"
+)
+
+// The result of importing a file of an archive.
+type ImportResult struct {
+	Path   string
+	Action ImportAction
+	// Why the file was skipped.
+	Reason string
+}
+
+func (r ImportResult) String() string {
+	if r.Reason != "" {
+		return fmt.Sprintf("%s %s: %s", r.Action, r.Path, r.Reason)
+	}
+	return fmt.Sprintf("%s %s", r.Action, r.Path)
+}
+
+// Returned when a file of an imported archive is invalid, nothing is imported.
+type ImportError struct {
+	Path string
+	Err  error
+}
+
+func (e ImportError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (e ImportError) Unwrap() error {
+	return e.Err
+}
+
+// Links to assets ie "/asset/cover.png", and asset shortcodes.
+var (
+	assetLink      = regexp.MustCompile(`/asset/([^\s"'()<>?#\]]+)`)
+	assetShortcode = regexp.MustCompile(`\{\{<\s*asset\s[^>]*name="([^"]+)"`)
+)
+
+// Writes a zip of the documents as markdown with their front matter in the
+// documents directory, and the assets they reference in the assets directory.
+func (ds Store) Export(w io.Writer) error {
+	docs, err := ds.GetAll()
+	if err != nil {
+		return err
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Id() < docs[j].Id()
+	})
+	archive := zip.NewWriter(w)
+	assets := make([]string, 0)
+	for _, doc := range docs {
+		f, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     path.Join(ArchiveDocuments, doc.Slug()+".md"),
+			Method:   zip.Deflate,
+			Modified: doc.Created(),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, doc.FrontMatter().Prepend(doc.Content()))
+		if err != nil {
+			return err
+		}
+		for _, name := range assetNames(doc) {
+			if !slices.Contains(assets, name) {
+				assets = append(assets, name)
+			}
+		}
+	}
+	sort.Strings(assets)
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	for _, name := range assets {
+		row, err := queries.GetAssetByName(ctx, name)
+		// Broken links are not exported.
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		f, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     path.Join(ArchiveAssets, name),
+			Method:   zip.Deflate,
+			Modified: row.Asset.Created,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(row.Asset.Content)
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// Imports the markdown files of the archive as documents, and the files in
+// its assets directory as assets, in a single transaction. The archive can be
+// an export, or any directory of markdown files.
+//
+// Documents are matched with existing ones by their slug, from the front
+// matter or the file name, and then by the id in the front matter. Unchanged
+// documents and assets are skipped.
+func (ds Store) Import(archive fs.FS) ([]ImportResult, error) {
+	// The documents are matched with all the documents, not only the filtered
+	// ones.
+	all := CreateStore(ds.db)
+	ctx := context.TODO()
+	tx, err := ds.db.BeginTx(ctx, nil)
+	defer tx.Rollback()
+	if err != nil {
+		return nil, err
+	}
+	queries := data.New(ds.db).WithTx(tx)
+	results := make([]ImportResult, 0)
+	err = fs.WalkDir(archive, ".", func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Hidden files ie ".git".
+		if p != "." && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		var action ImportAction
+		switch ext := strings.ToLower(path.Ext(p)); {
+		case strings.HasPrefix(p, ArchiveAssets+"/"):
+			action, err = importAsset(ctx, queries, archive, p)
+		case ext == ".md" || ext == ".markdown":
+			action, err = all.importDocument(ctx, queries, archive, p)
+		default:
+			results = append(results, ImportResult{Path: p, Action: Skipped, Reason: "Not a markdown file"})
+			return nil
+		}
+		if err != nil {
+			return ImportError{Path: p, Err: err}
+		}
+		result := ImportResult{Path: p, Action: action}
+		if action == Skipped {
+			result.Reason = "Unchanged"
+		}
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (ds Store) importDocument(ctx context.Context, queries *data.Queries, archive fs.FS, p string) (ImportAction, error) {
+	content, err := fs.ReadFile(archive, p)
+	if err != nil {
+		return "", err
+	}
+	fm, body, err := markdown.ParseFrontMatter(string(content))
+	if err != nil {
+		return "", err
+	}
+	if fm == nil {
+		fm = new(markdown.FrontMatter)
+	}
+	if fm.Status != "" {
+		if _, err := ParseStatus(fm.Status); err != nil {
+			return "", err
+		}
+	}
+	name := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	slug := fm.Slug
+	if slug == "" {
+		slug = name
+	}
+	existing, found, err := ds.findImported(ctx, queries, normalizeSlug(slug, fm.Title), fm.Id)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		proto := ProtoDocument{
+			Title:   name,
+			Content: body,
+			Created: time.Now(),
+			Slug:    slug,
+		}
+		setFrontMatter(&proto, fm)
+		_, err = ds.add(ctx, queries, proto)
+		return Created, err
+	}
+	proto := existing.proto()
+	proto.Content = body
+	setFrontMatter(&proto, fm)
+	if sameDocument(proto, existing.proto()) {
+		return Skipped, nil
+	}
+	_, err = existing.update(ctx, queries, proto)
+	return Updated, err
+}
+
+// Returns the document with the slug, or the id if there is none. They are
+// looked up in the transaction, so that the documents imported before are
+// found.
+func (ds Store) findImported(ctx context.Context, queries *data.Queries, slug string, id int64) (Document, bool, error) {
+	rows := make([]data.GetDocumentsRow, 0)
+	bySlug, err := queries.GetDocumentBySlug(ctx, slug)
+	if err != nil {
+		return Document{}, false, err
+	}
+	for _, row := range bySlug {
+		rows = append(rows, data.GetDocumentsRow(row))
+	}
+	if len(rows) == 0 && id != 0 {
+		byId, err := queries.GetDocument(ctx, id)
+		if err != nil {
+			return Document{}, false, err
+		}
+		for _, row := range byId {
+			rows = append(rows, data.GetDocumentsRow(row))
+		}
+	}
+	if len(rows) == 0 {
+		return Document{}, false, nil
+	}
+	return documentsFromRows(ds.db, rows)[0], true, nil
+}
+
+// Reports if updating a document from one prototype to the other would not
+// change it. Front matter dates are only precise to the second.
+func sameDocument(a, b ProtoDocument) bool {
+	return a.Title == b.Title &&
+		a.Content == b.Content &&
+		a.Created.Truncate(time.Second).Equal(b.Created.Truncate(time.Second)) &&
+		slices.Equal(tagValues(a.Tags), tagValues(b.Tags)) &&
+		a.Summary == b.Summary &&
+		normalizeSlug(a.Slug, a.Title) == normalizeSlug(b.Slug, b.Title) &&
+		a.Cover == b.Cover &&
+		a.Status == b.Status &&
+		a.PublishAt.Truncate(time.Second).Equal(b.PublishAt.Truncate(time.Second))
+}
+
+func importAsset(ctx context.Context, queries *data.Queries, archive fs.FS, p string) (ImportAction, error) {
+	content, err := fs.ReadFile(archive, p)
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimPrefix(p, ArchiveAssets+"/")
+	action := Updated
+	row, err := queries.GetAssetByName(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		action = Created
+	} else if err != nil {
+		return "", err
+	} else if bytes.Equal(row.Asset.Content, content) {
+		return Skipped, nil
+	}
+	_, err = queries.CreateAsset(ctx, data.CreateAssetParams{
+		Name:    name,
+		Content: content,
+	})
+	return action, err
+}
+
+// Returns the names of the assets the document links to, in order.
+func assetNames(d Document) []string {
+	names := make([]string, 0)
+	add := func(name string) {
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, text := range []string{d.Cover(), d.Content()} {
+		for _, match := range assetLink.FindAllStringSubmatch(text, -1) {
+			add(match[1])
+		}
+		for _, match := range assetShortcode.FindAllStringSubmatch(text, -1) {
+			add(match[1])
+		}
+	}
+	return names
+}
@@ -0,0 +1,128 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAssetNames(t *testing.T) {
+	doc := Document{
+		cover:   "/asset/cover.png",
+		content: "![Image](/asset/a%20b.png) [link](/asset/doc.pdf?w=640)\n{{< asset name=\"c.zip\" text=\"Download\" >}}\n![Again](/asset/cover.png)",
+	}
+	expected := []string{"cover.png", "a b.png", "doc.pdf", "c.zip"}
+	if names := assetNames(doc); !slices.Equal(names, expected) {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestSameDocument(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 600, time.UTC)
+	a := ProtoDocument{Title: "Hello", Content: "Body", Created: created, Status: Published}
+	b := a
+	b.Created = created.Truncate(time.Second)
+	b.Slug = "hello"
+	if !sameDocument(a, b) {
+		t.Fatal("Expected documents that only differ by precision and slug normalization to be the same")
+	}
+	b.Content = "Changed"
+	if sameDocument(a, b) {
+		t.Fatal("Expected documents with different content to be different")
+	}
+}
+
+func TestImportResult(t *testing.T) {
+	r := ImportResult{Path: "documents/a.md", Action: Created}
+	if r.String() != "created documents/a.md" {
+		t.Fatalf("Expected 'created documents/a.md', got '%s'", r)
+	}
+	r = ImportResult{Path: "a.txt", Action: Skipped, Reason: "Not a markdown file"}
+	if r.String() != "skipped a.txt: Not a markdown file" {
+		t.Fatalf("Expected 'skipped a.txt: Not a markdown file', got '%s'", r)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	setupSampleData(con)
+	buf := new(bytes.Buffer)
+	err := ds.Export(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := ds.Import(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Action != Skipped {
+			t.Fatalf("Expected the exported documents to be unchanged, got %s", r)
+		}
+	}
+	results, err = ds.Import(fstest.MapFS{
+		"new.md":    {Data: []byte("---\ntitle: New\ntags: [imported]\n---\n\nBody\n")},
+		"notes.txt": {Data: []byte("Not markdown")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Action != Created || results[1].Action != Skipped {
+		t.Fatalf("Expected new.md to be created and notes.txt skipped, got %v", results)
+	}
+	doc, err := ds.GetBySlug("new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title() != "New" || doc.Content() != "Body\n" || len(doc.Tags()) != 1 {
+		t.Fatalf("Expected the imported document, got %+v", doc.FrontMatter())
+	}
+}
+
+func TestImportIsAtomic(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	_, err := ds.Import(fstest.MapFS{
+		"a.md": {Data: []byte("# A\n")},
+		"b.md": {Data: []byte("---\nstatus: unknown\n---\n")},
+	})
+	if _, ok := err.(ImportError); !ok {
+		t.Fatalf("Expected an import error, got %v", err)
+	}
+	if _, err := ds.GetBySlug("a"); err == nil {
+		t.Fatal("Expected nothing to be imported")
+	}
+}
+
+func TestImportSameDocumentTwice(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	results, err := ds.Import(fstest.MapFS{
+		"a/foo.md": {Data: []byte("First\n")},
+		"b/foo.md": {Data: []byte("Second\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Action != Created || results[1].Action != Updated {
+		t.Fatalf("Expected foo to be created then updated, got %v", results)
+	}
+	doc, err := ds.GetBySlug("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Content() != "Second\n" {
+		t.Fatalf("Expected the second file's content, got %q", doc.Content())
+	}
+	if _, err := ds.GetBySlug("foo-2"); err == nil {
+		t.Fatal("Expected no second document")
+	}
+}
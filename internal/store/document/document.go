@@ -6,14 +6,23 @@ package document
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"samuellando.com/data"
+	"samuellando.com/internal/cache"
+	apperrors "samuellando.com/internal/errors"
 	"samuellando.com/internal/markdown"
+	"samuellando.com/internal/store"
 	"samuellando.com/internal/store/tag"
 )
 
@@ -23,16 +32,32 @@ type ProtoDocument struct {
 	Content string
 	Tags    []tag.ProtoTag
 	Created time.Time
+	Summary string
+	// Generated from the title if empty, and numbered if it is taken.
+	Slug  string
+	Cover string
+	// Defaults to Draft for new documents.
+	Status Status
+	// When a Scheduled document is published.
+	PublishAt time.Time
 }
 
 // An actual document in the database.
 type Document struct {
-	db      *sql.DB
-	id      int64
-	title   string
-	content string
-	tags    []tag.ProtoTag
-	created time.Time
+	db           *sql.DB
+	id           int64
+	title        string
+	content      string
+	tags         []tag.ProtoTag
+	created      time.Time
+	summary      string
+	slug         string
+	cover        string
+	status       Status
+	publishAt    time.Time
+	previewToken string
+	version      int64
+	deleted      time.Time
 }
 
 func (d Document) Id() int64 {
@@ -48,9 +73,65 @@ func (d Document) Content() string {
 	return d.content
 }
 
-func (d Document) Html() (template.HTML, error) {
+// The rendered html, table of contents and errors of documents, by the hash
+// of their content. It can be replaced to also cache in the database.
+var HtmlCache = cache.NewLRU(256)
+
+// Renders the document once for its html, table of contents and errors,
+// templates should use it instead of calling Html, TableOfContents and
+// ParseErrors separately.
+//
+// Blocks that can not be rendered are shown as text, and listed in Errors.
+func (d Document) Rendered() (markdown.Rendered, error) {
 	content := d.Content()
-	return markdown.ToHtml(content)
+	// Shortcodes embed live data, so their html can not be cached.
+	if strings.Contains(content, "{{<") {
+		return markdown.Render(content, markdown.Lenient)
+	}
+	data, err := HtmlCache.Get(htmlCacheKey(content), func() ([]byte, error) {
+		rendered, err := markdown.Render(content, markdown.Lenient)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(rendered)
+	})
+	if err != nil {
+		return markdown.Rendered{}, err
+	}
+	var rendered markdown.Rendered
+	err = json.Unmarshal(data, &rendered)
+	return rendered, err
+}
+
+// Blocks that can not be rendered are shown as text, see ParseErrors.
+func (d Document) Html() (template.HTML, error) {
+	rendered, err := d.Rendered()
+	return rendered.Html, err
+}
+
+func htmlCacheKey(content string) string {
+	return "rendered:" + contentHash(content)
+}
+
+func contentHash(content string) string {
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "%d\x00%s", markdown.RendererVersion, content)
+	return base64.URLEncoding.EncodeToString(hasher.Sum(nil))
+}
+
+// The errors of the blocks that could not be rendered.
+func (d Document) ParseErrors() []*markdown.ParseError {
+	rendered, err := d.Rendered()
+	if err != nil {
+		return nil
+	}
+	return rendered.Errors
+}
+
+// The nested outline of the document's headings.
+func (d Document) TableOfContents() ([]markdown.Heading, error) {
+	rendered, err := d.Rendered()
+	return rendered.TableOfContents, err
 }
 
 func (d Document) Tags() []tag.ProtoTag {
@@ -61,52 +142,198 @@ func (d Document) Created() time.Time {
 	return d.created
 }
 
+func (d Document) Summary() string {
+	return d.summary
+}
+
+func (d Document) Slug() string {
+	return d.slug
+}
+
+// The number of times the document was updated, starting at 1.
+func (d Document) Version() int64 {
+	return d.version
+}
+
+// The public page of the document.
+func (d Document) Url() string {
+	return "/documents/" + url.PathEscape(d.slug)
+}
+
+// A link to the cover image, or an empty string.
+func (d Document) Cover() string {
+	return d.cover
+}
+
+// Returns the document's metadata, as written in the front matter on export.
+func (d Document) FrontMatter() markdown.FrontMatter {
+	return markdown.FrontMatter{
+		Id:        d.Id(),
+		Title:     d.Title(),
+		Tags:      tagValues(d.Tags()),
+		Created:   d.Created(),
+		Summary:   d.Summary(),
+		Slug:      d.Slug(),
+		Cover:     d.Cover(),
+		Status:    string(d.Status()),
+		PublishAt: d.PublishAt(),
+	}
+}
+
+// The plain text of documents, by the hash of their content. Search and
+// related documents index every document on each request, so the content is
+// only parsed when it changes.
+var TextCache = cache.NewLRU(1024, func(co *cache.CacheOptions) {
+	co.MaxAge = 24 * time.Hour
+})
+
+// Returns the text used to index the document, the content is indexed without
+// its markup when it can be parsed.
 func (d Document) ToString() string {
-	content := d.Content()
-	s := fmt.Sprintf("%s\n%s\n%s", d.Title(), content, strings.Join(tagValues(d.Tags()), " "))
+	s := fmt.Sprintf("%s\n%s\n%s", d.Title(), plainText(d.Content()), strings.Join(tagValues(d.Tags()), " "))
 	return s
 }
 
+// Returns the content without its markup, or as is if it can not be parsed.
+func plainText(content string) string {
+	data, _ := TextCache.Get("text:"+contentHash(content), func() ([]byte, error) {
+		root, err := markdown.Parse(content)
+		if err != nil {
+			return []byte(content), nil
+		}
+		return []byte(root.PlainText()), nil
+	})
+	return string(data)
+}
+
+// The lint issues of contents being edited, by their hash.
+var LintCache = cache.NewLRU(64, func(co *cache.CacheOptions) {
+	co.MaxAge = time.Hour
+})
+
+type lintResult struct {
+	Issues     []markdown.LintIssue
+	ParseError *markdown.ParseError
+}
+
+// Returns the style issues of the content, see markdown.Lint. The content is
+// only parsed when it changed.
+func lint(content string) ([]markdown.LintIssue, error) {
+	data, err := LintCache.Get("lint:"+contentHash(content), func() ([]byte, error) {
+		issues, err := markdown.Lint(content)
+		var parseErr *markdown.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return nil, err
+		}
+		return json.Marshal(lintResult{Issues: issues, ParseError: parseErr})
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result lintResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	if result.ParseError != nil {
+		return nil, result.ParseError
+	}
+	return result.Issues, nil
+}
+
 // Update a document
 //
-// everything is deep copied, and rolled back in case of an error
+// everything is deep copied, and rolled back in case of an error.
+// The updated document is added to its revisions, if its title, content or
+// tags changed.
+//
+// Returns a ConflictError if the document was updated since it was loaded.
 func (d *Document) Update(setters ...func(*ProtoDocument)) error {
-	p := ProtoDocument{
-		Title:   d.Title(),
-		Content: d.Content(),
-		Created: d.Created(),
-		Tags:    d.Tags(),
-	}
+	p := d.proto()
 	for _, setter := range setters {
 		setter(&p)
 	}
-
 	ctx := context.TODO()
 	tx, err := d.db.BeginTx(ctx, nil)
 	defer tx.Rollback()
 	if err != nil {
 		return err
 	}
-	queries := data.New(d.db).WithTx(tx)
-	err = queries.UpdateDocument(ctx, data.UpdateDocumentParams{
-		ID:      d.Id(),
-		Title:   p.Title,
-		Content: p.Content,
-		Created: p.Created,
-	})
+	updated, err := d.update(ctx, data.New(d.db).WithTx(tx), p)
 	if err != nil {
 		return err
 	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	*d = updated
+	return nil
+}
+
+// Returns the prototype of the document, with its current values.
+func (d Document) proto() ProtoDocument {
+	return ProtoDocument{
+		Title:     d.Title(),
+		Content:   d.Content(),
+		Created:   d.Created(),
+		Tags:      d.Tags(),
+		Summary:   d.Summary(),
+		Slug:      d.Slug(),
+		Cover:     d.Cover(),
+		Status:    d.Status(),
+		PublishAt: d.PublishAt(),
+	}
+}
+
+// Updates the document with the queries of a transaction, and returns the
+// updated copy.
+func (d Document) update(ctx context.Context, queries *data.Queries, p ProtoDocument) (Document, error) {
+	if err := checkStatus(&p); err != nil {
+		return Document{}, err
+	}
+	var err error
+	p.Slug, err = uniqueSlug(ctx, queries, normalizeSlug(p.Slug, p.Title), d.id)
+	if err != nil {
+		return Document{}, err
+	}
+	updated, err := queries.UpdateDocument(ctx, data.UpdateDocumentParams{
+		ID:        d.Id(),
+		Title:     p.Title,
+		Content:   p.Content,
+		Created:   p.Created,
+		Summary:   p.Summary,
+		Slug:      p.Slug,
+		Status:    string(p.Status),
+		PublishAt: store.NullTime(p.PublishAt),
+		Cover:     p.Cover,
+		Version:   d.version,
+	})
+	if err != nil {
+		return Document{}, err
+	}
+	if updated == 0 {
+		return Document{}, apperrors.CreateConflictError("Document")
+	}
+	err = moveSlug(ctx, queries, d.id, d.slug, p.Slug)
+	if err != nil {
+		return Document{}, err
+	}
 	tagRows, err := queries.SetDocumentTags(ctx, data.SetDocumentTagsParams{
 		Document:  d.id,
 		TagValues: tagValues(p.Tags),
 	})
 	if err != nil {
-		return err
+		return Document{}, err
 	}
-	err = tx.Commit()
+	if p.Title != d.title || p.Content != d.content || !slices.Equal(tagValues(p.Tags), tagValues(d.tags)) {
+		err = addRevision(ctx, queries, d.id, p)
+		if err != nil {
+			return Document{}, err
+		}
+	}
+	err = setLinks(ctx, queries, d.id, p.Slug, p.Content)
 	if err != nil {
-		return err
+		return Document{}, err
 	}
 	tags := make([]tag.ProtoTag, len(tagRows))
 	for i, tagRow := range tagRows {
@@ -118,14 +345,21 @@ func (d *Document) Update(setters ...func(*ProtoDocument)) error {
 	d.title = p.Title
 	d.content = p.Content
 	d.created = p.Created
+	d.summary = p.Summary
+	d.slug = p.Slug
+	d.status = p.Status
+	d.publishAt = p.PublishAt
+	d.version++
+	d.cover = p.Cover
 	d.tags = tags
-	return nil
+	return d, nil
 }
 
+// Moves the document to the trash, see Store.Restore and Store.Purge.
 func (d Document) Delete() error {
 	ctx := context.TODO()
 	queries := data.New(d.db)
-	err := queries.DeleteDocument(ctx, d.id)
+	err := queries.TrashDocument(ctx, d.id)
 	return err
 }
 
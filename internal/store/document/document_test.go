@@ -1,9 +1,17 @@
 package document
 
 import (
+	"errors"
+	"html/template"
+	"io"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"samuellando.com/internal/cache"
+	apperrors "samuellando.com/internal/errors"
+	"samuellando.com/internal/markdown"
 	"samuellando.com/internal/store/tag"
 )
 
@@ -106,6 +114,39 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateMetadata(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	doc, err := ds.Add(ProtoDocument{
+		Title:   "Sample",
+		Content: "Content",
+		Created: time.Now(),
+		Summary: "Summary",
+		Status:  Draft,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = doc.Update(func(pd *ProtoDocument) {
+		if pd.Summary != "Summary" || pd.Status != Draft {
+			t.Fatal("Metadata not defaulted")
+		}
+		pd.Slug = "sample"
+		pd.Cover = "/asset/cover.png"
+		pd.Status = Published
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = ds.GetById(doc.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Summary() != "Summary" || doc.Slug() != "sample" || doc.Cover() != "/asset/cover.png" || doc.Status() != Published {
+		t.Fatalf("expected updated metadata, got '%+v'", doc.FrontMatter())
+	}
+}
+
 func TestDelete(t *testing.T) {
 	ds, con := setup()
 	defer teardown(con)
@@ -123,3 +164,165 @@ func TestDelete(t *testing.T) {
 		t.Fatal("Should throw an error")
 	}
 }
+
+func TestHtmlCache(t *testing.T) {
+	defer func(c *cache.LRU) { HtmlCache = c }(HtmlCache)
+	HtmlCache = cache.NewLRU(2)
+	doc := Document{content: "# Cached"}
+	html, err := doc.Html()
+	if err != nil || !strings.Contains(string(html), "Cached") {
+		t.Fatalf("Expected the rendered html, got %s, error: %v", html, err)
+	}
+	if HtmlCache.Len() != 1 {
+		t.Fatalf("Expected the html to be cached, got %d entries", HtmlCache.Len())
+	}
+	cached, err := doc.Html()
+	if err != nil || cached != html {
+		t.Fatalf("Expected the cached html, got %s, error: %v", cached, err)
+	}
+	if htmlCacheKey("a") == htmlCacheKey("b") {
+		t.Fatal("Expected different content to have different keys")
+	}
+	doc = Document{content: "{{< live >}}"}
+	doc.Html()
+	if HtmlCache.Len() != 1 {
+		t.Fatal("Expected shortcodes not to be cached")
+	}
+}
+
+// Renders the document component of the document page, which shows the html,
+// the errors and the table of contents.
+func BenchmarkHtml(b *testing.B) {
+	defer func(c *cache.LRU) { HtmlCache = c }(HtmlCache)
+	content := strings.Repeat("# Heading\n\nSome *text* with a [link](/x), `code` and **bold**.\n\n- one\n- two\n\n", 20)
+	components := template.New("components")
+	for _, name := range []string{"document", "toc"} {
+		source, err := os.ReadFile("../../../templates/components/" + name + ".html")
+		if err != nil {
+			b.Fatal(err)
+		}
+		template.Must(components.New(name).Parse(string(source)))
+	}
+	execute := func(doc Document) {
+		if err := components.ExecuteTemplate(io.Discard, "document", doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.Run("Uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			HtmlCache = cache.NewLRU(0)
+			execute(Document{content: content})
+		}
+	})
+	b.Run("Cached", func(b *testing.B) {
+		HtmlCache = cache.NewLRU(1)
+		doc := Document{content: content}
+		execute(doc)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			execute(doc)
+		}
+	})
+}
+
+func TestRenderedCache(t *testing.T) {
+	defer func(c *cache.LRU) { HtmlCache = c }(HtmlCache)
+	HtmlCache = cache.NewLRU(2)
+	doc := Document{content: "# Title\n\n## Part\n\nbad \f here\n"}
+	rendered, err := doc.Rendered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rendered.Errors) != 1 || len(rendered.TableOfContents) != 1 || len(rendered.TableOfContents[0].Children) != 1 {
+		t.Fatalf("Expected an error and the outline, got %+v", rendered)
+	}
+	cached, err := doc.Rendered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if HtmlCache.Len() != 1 || cached.Html != rendered.Html || len(cached.Errors) != 1 || cached.Errors[0].Line != 5 {
+		t.Fatalf("Expected the cached render, got %+v", cached)
+	}
+}
+
+func TestTextCache(t *testing.T) {
+	defer func(c *cache.LRU) { TextCache = c }(TextCache)
+	TextCache = cache.NewLRU(2)
+	doc := Document{title: "Title", content: "Some *emphasis*, and `code`."}
+	for range 2 {
+		text := doc.ToString()
+		if text != "Title\nSome emphasis, and code.\n" {
+			t.Fatalf("Expected the content without its markup, got %q", text)
+		}
+	}
+	if TextCache.Len() != 1 {
+		t.Fatalf("Expected the plain text to be cached once, got %d entries", TextCache.Len())
+	}
+	unparsed := Document{content: "bad \f here"}
+	if text := unparsed.ToString(); text != "\nbad \f here\n" {
+		t.Fatalf("Expected the content as is when it can not be parsed, got %q", text)
+	}
+}
+
+func TestLintCache(t *testing.T) {
+	defer func(c *cache.LRU) { LintCache = c }(LintCache)
+	LintCache = cache.NewLRU(2)
+	for range 2 {
+		issues, err := lint("# Title\n\n![](/asset/a.png)\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) != 1 || issues[0].Check != "image-alt" || issues[0].Line != 3 {
+			t.Fatalf("Expected the image without alt text, got %v", issues)
+		}
+	}
+	if LintCache.Len() != 1 {
+		t.Fatalf("Expected the issues to be cached once, got %d entries", LintCache.Len())
+	}
+	for range 2 {
+		_, err := lint("bad \f here")
+		var parseErr *markdown.ParseError
+		if !errors.As(err, &parseErr) || parseErr.Line != 1 {
+			t.Fatalf("Expected a parse error on line 1, got %v", err)
+		}
+	}
+}
+
+func TestUpdateConflict(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	doc, err := ds.Add(ProtoDocument{Title: "Sample", Content: "Content", Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab1, err := ds.GetById(doc.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab2, err := ds.GetById(doc.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tab1.Update(func(pd *ProtoDocument) {
+		pd.Content = "First"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tab1.Version() != doc.Version()+1 {
+		t.Fatalf("Expected version %d, got %d", doc.Version()+1, tab1.Version())
+	}
+	err = tab2.Update(func(pd *ProtoDocument) {
+		pd.Content = "Second"
+	})
+	if !errors.As(err, new(apperrors.ConflictError)) {
+		t.Fatalf("Expected a conflict error, got %v", err)
+	}
+	current, err := ds.GetById(doc.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Content() != "First" {
+		t.Fatalf("Expected the first update to be kept, got '%s'", current.Content())
+	}
+}
@@ -1,20 +1,27 @@
 package document
 
 import (
+	"archive/zip"
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"html/template"
+	apperrors "samuellando.com/internal/errors"
+	"samuellando.com/internal/etag"
+	"samuellando.com/internal/markdown"
 	"samuellando.com/internal/store/tag"
 )
 
 type Handler struct {
 	Template      template.Template
+	LintTemplate  template.Template
 	DocumentStore Store
 	TagStore      tag.Store
 }
@@ -28,9 +35,17 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 			h.templateRequest(w, req)
 		}
 	case "POST":
-		h.createDocument(w, req)
+		if req.FormValue("lint") != "" {
+			h.lintDocument(w, req)
+		} else {
+			h.createDocument(w, req)
+		}
 	case "PUT":
-		h.updateDocument(w, req)
+		if req.FormValue("restore") != "" {
+			h.restoreRevision(w, req)
+		} else {
+			h.updateDocument(w, req)
+		}
 	case "DELETE":
 		h.deleteDocument(w, req)
 	}
@@ -42,12 +57,41 @@ func (h *Handler) templateRequest(w http.ResponseWriter, req *http.Request) {
 }
 
 func (h *Handler) renderDocument(w http.ResponseWriter, doc Document) {
+	if doc.Id() != 0 {
+		etag.Set(w, doc.Version())
+	}
 	err := h.Template.Execute(w, doc)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
 	}
 }
 
+// Checks that the request is based on the current version of the document,
+// otherwise responds with an error and returns false.
+func (h *Handler) checkVersion(w http.ResponseWriter, req *http.Request, doc Document) bool {
+	match, err := etag.Match(req, doc.Version())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(428), err), 428)
+		return false
+	}
+	if !match {
+		h.conflict(w, req)
+	}
+	return match
+}
+
+// Responds with the current version of the document, when the request was
+// based on an older one.
+func (h *Handler) conflict(w http.ResponseWriter, req *http.Request) {
+	doc := h.getReqDoc(req)
+	etag.Set(w, doc.Version())
+	w.WriteHeader(http.StatusConflict)
+	err := h.Template.Execute(w, doc)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
 func (h *Handler) getReqDoc(req *http.Request) Document {
 	id, err := strconv.Atoi(req.PathValue("document"))
 	if err != nil {
@@ -66,22 +110,86 @@ func (h *Handler) downloadDocument(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
 	w.Header().Set("Content-Type", "text/markdown")
 	w.WriteHeader(http.StatusOK)
-	content := doc.Content()
+	// Include the metadata, so that uploading the file restores it.
+	content := doc.FrontMatter().Prepend(doc.Content())
 	_, err := w.Write([]byte(content))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
 	}
 }
 
+// Streams a zip of every document and the assets they reference, see
+// Store.Export.
+func (h *Handler) ExportDocuments(w http.ResponseWriter, req *http.Request) {
+	filename := fmt.Sprintf("documents-%s.zip", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Header().Set("Content-Type", "application/zip")
+	// The archive is streamed, the status can not be changed once it starts.
+	err := h.DocumentStore.Export(w)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// Imports the uploaded zip, see Store.Import, and responds with what happened
+// to each file.
+func (h *Handler) ImportDocuments(w http.ResponseWriter, req *http.Request) {
+	const max_archive_size = int64(100000000)
+	f, header, err := req.FormFile("file")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), "No file provided"), 400)
+		return
+	}
+	defer f.Close()
+	if header.Size > max_archive_size {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(413), "File too large (100MB max)"), 413)
+		return
+	}
+	archive, err := zip.NewReader(f, header.Size)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), err), 400)
+		return
+	}
+	results, err := h.DocumentStore.Import(archive)
+	if errors.As(err, new(ImportError)) {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), err), 400)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, result := range results {
+		fmt.Fprintln(w, result)
+	}
+}
+
 func (h *Handler) createDocument(w http.ResponseWriter, req *http.Request) {
 	title := req.PostFormValue("title")
-	content := req.PostFormValue("content")
+	content, fm, err, err_code := getUploadDocument(req)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), err_code)
+		return
+	}
+	if content == "" {
+		content = req.PostFormValue("content")
+	}
 	tags := h.getTagsFromReq(req)
-	doc, err := h.DocumentStore.Add(ProtoDocument{
+	p := ProtoDocument{
 		Title:   title,
 		Content: content,
 		Tags:    tags,
-	})
+		Slug:    req.PostFormValue("slug"),
+	}
+	setFrontMatter(&p, fm)
+	if err := setStatusFromReq(req, &p); err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), err), 400)
+		return
+	}
+	doc, err := h.DocumentStore.Add(p)
 	if err != nil {
 		log.Println(err)
 		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
@@ -89,6 +197,24 @@ func (h *Handler) createDocument(w http.ResponseWriter, req *http.Request) {
 	}
 	h.renderDocument(w, doc)
 }
+
+// Reports the style issues of the content, without saving it.
+func (h *Handler) lintDocument(w http.ResponseWriter, req *http.Request) {
+	issues, err := lint(req.PostFormValue("content"))
+	var parseErr *markdown.ParseError
+	if err != nil && !errors.As(err, &parseErr) {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
+		return
+	}
+	err = h.LintTemplate.Execute(w, map[string]any{
+		"Issues":     issues,
+		"ParseError": parseErr,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
+	}
+}
+
 func (h *Handler) getTagsFromReq(req *http.Request) []tag.ProtoTag {
 	tagValues := strings.Split(req.PostFormValue("tags"), ",")
 	tags := make([]tag.ProtoTag, 0)
@@ -101,10 +227,41 @@ func (h *Handler) getTagsFromReq(req *http.Request) []tag.ProtoTag {
 	return tags
 }
 
+// Sets the status and publish time of the document from the "status" and
+// "publish_at" fields, if they are set.
+func setStatusFromReq(req *http.Request, p *ProtoDocument) error {
+	if s := req.PostFormValue("status"); s != "" {
+		status, err := ParseStatus(s)
+		if err != nil {
+			return err
+		}
+		p.Status = status
+	}
+	if s, ok := req.PostForm["publish_at"]; ok {
+		p.PublishAt = time.Time{}
+		if s[0] != "" {
+			// The format of datetime-local inputs.
+			t, err := time.ParseInLocation("2006-01-02T15:04", s[0], time.Local)
+			if err != nil {
+				return fmt.Errorf("Invalid publish time '%s'", s[0])
+			}
+			p.PublishAt = t
+		}
+	}
+	return nil
+}
+
 func (h *Handler) updateDocument(w http.ResponseWriter, req *http.Request) {
 	doc := h.getReqDoc(req)
+	if doc.Id() == 0 {
+		http.NotFound(w, req)
+		return
+	}
+	if !h.checkVersion(w, req, doc) {
+		return
+	}
 	title := req.PostFormValue("title")
-	content, err, err_code := getUploadContent(req)
+	content, fm, err, err_code := getUploadDocument(req)
 	if err != nil {
 		log.Println(err)
 		http.Error(w, err.Error(), err_code)
@@ -114,11 +271,27 @@ func (h *Handler) updateDocument(w http.ResponseWriter, req *http.Request) {
 		content = req.PostFormValue("content")
 	}
 	tags := h.getTagsFromReq(req)
+	status := ProtoDocument{Status: doc.Status(), PublishAt: doc.PublishAt()}
+	if err := setStatusFromReq(req, &status); err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), err), 400)
+		return
+	}
 	err = doc.Update(func(df *ProtoDocument) {
 		df.Title = title
 		df.Content = content
 		df.Tags = tags
+		df.Status = status.Status
+		df.PublishAt = status.PublishAt
+		// An empty slug is generated from the title again.
+		if slug, ok := req.PostForm["slug"]; ok {
+			df.Slug = slug[0]
+		}
+		setFrontMatter(df, fm)
 	})
+	if errors.As(err, new(apperrors.ConflictError)) {
+		h.conflict(w, req)
+		return
+	}
 	if err != nil {
 		log.Println(err)
 		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
@@ -128,8 +301,53 @@ func (h *Handler) updateDocument(w http.ResponseWriter, req *http.Request) {
 	h.renderDocument(w, doc)
 }
 
+func (h *Handler) restoreRevision(w http.ResponseWriter, req *http.Request) {
+	doc := h.getReqDoc(req)
+	if doc.Id() == 0 {
+		http.NotFound(w, req)
+		return
+	}
+	if !h.checkVersion(w, req, doc) {
+		return
+	}
+	id, err := strconv.Atoi(req.FormValue("restore"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), err), 400)
+		return
+	}
+	err = doc.RestoreRevision(int64(id))
+	if errors.As(err, new(apperrors.ConflictError)) {
+		h.conflict(w, req)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
+		return
+	}
+	h.renderDocument(w, doc)
+}
+
 func (h *Handler) deleteDocument(w http.ResponseWriter, req *http.Request) {
 	doc := h.getReqDoc(req)
+	if doc.Id() == 0 {
+		http.NotFound(w, req)
+		return
+	}
+	// Documents that others link to are only deleted when forced, their links
+	// would break.
+	if req.FormValue("force") != "true" {
+		backlinks, err := doc.Backlinks()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
+			return
+		}
+		if len(backlinks) > 0 {
+			err = fmt.Errorf("Document is linked from %d other documents", len(backlinks))
+			http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(409), err), 409)
+			return
+		}
+	}
 	err := doc.Delete()
 	// Failed to delete
 	if err != nil {
@@ -139,6 +357,63 @@ func (h *Handler) deleteDocument(w http.ResponseWriter, req *http.Request) {
 	fmt.Fprint(w, "ok")
 }
 
+// Returns the contents of the uploaded file without its front matter, and the
+// front matter if there is one.
+// Returns a non nil error if there are any problems, along with a status code.
+func getUploadDocument(req *http.Request) (string, *markdown.FrontMatter, error, int) {
+	content, err, err_code := getUploadContent(req)
+	if err != nil || content == "" {
+		return content, nil, err, err_code
+	}
+	fm, content, err := markdown.ParseFrontMatter(content)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s : %s", http.StatusText(400), err), 400
+	}
+	if fm != nil && fm.Status != "" {
+		if _, err := ParseStatus(fm.Status); err != nil {
+			return "", nil, fmt.Errorf("%s : %s", http.StatusText(400), err), 400
+		}
+	}
+	return content, fm, nil, 0
+}
+
+// Overrides the fields of the document that are set in the front matter.
+func setFrontMatter(p *ProtoDocument, fm *markdown.FrontMatter) {
+	if fm == nil {
+		return
+	}
+	if fm.Title != "" {
+		p.Title = fm.Title
+	}
+	if len(fm.Tags) > 0 {
+		p.Tags = make([]tag.ProtoTag, len(fm.Tags))
+		for i, value := range fm.Tags {
+			p.Tags[i] = tag.ProtoTag{Value: value}
+		}
+	}
+	if !fm.Created.IsZero() {
+		p.Created = fm.Created
+	}
+	if fm.Summary != "" {
+		p.Summary = fm.Summary
+	}
+	if fm.Slug != "" {
+		p.Slug = fm.Slug
+	}
+	if fm.Cover != "" {
+		p.Cover = fm.Cover
+	}
+	if fm.Draft {
+		p.Status = Draft
+	}
+	if status, err := ParseStatus(fm.Status); err == nil {
+		p.Status = status
+	}
+	if !fm.PublishAt.IsZero() {
+		p.PublishAt = fm.PublishAt
+	}
+}
+
 // Returns the contents of the uploaded file int the "file" field.
 // Returns an empty string if there is no file
 // Returns a non nil error if there are any problems, along with a status code.
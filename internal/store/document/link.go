@@ -0,0 +1,215 @@
+package document
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"samuellando.com/data"
+	"samuellando.com/internal/markdown"
+)
+
+// A link from a document to another document.
+type Link struct {
+	source int64
+	href   string
+	target int64
+	broken bool
+}
+
+// The id of the document the link is in.
+func (l Link) Source() int64 {
+	return l.source
+}
+
+// The link as written in the document, ie "/documents/intro#setup".
+func (l Link) Href() string {
+	return l.href
+}
+
+// The id of the linked document, or 0 if no document has the reference.
+func (l Link) Target() int64 {
+	return l.target
+}
+
+// Reports if the linked document does not exist, or is in the trash.
+func (l Link) Broken() bool {
+	return l.broken
+}
+
+// An internal link found in the content of a document.
+type internalLink struct {
+	href string
+	// The slug or id of the linked document.
+	ref string
+}
+
+// Returns the links of the document to other documents: the links to their
+// pages, and the document shortcodes.
+//
+// Documents that can not be parsed have no links.
+func internalLinks(content string) []internalLink {
+	root, err := markdown.Parse(content)
+	if err != nil {
+		return nil
+	}
+	links := make([]internalLink, 0)
+	root.Walk(func(n *markdown.Node) bool {
+		switch {
+		case n.Kind == markdown.LinkNode:
+			if ref := documentRef(n.Href); ref != "" {
+				links = append(links, internalLink{href: n.Href, ref: ref})
+			}
+		case n.Kind == markdown.ShortcodeNode && n.Label == "document":
+			if id := n.Args["id"]; id != "" {
+				links = append(links, internalLink{href: "/documents/" + id, ref: id})
+			}
+		}
+		return true
+	})
+	return links
+}
+
+// Returns the slug or id of the document page the url points to, ie "intro"
+// for "/documents/intro#setup", or an empty string.
+func documentRef(href string) string {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || u.Host != "" || u.Scheme != "" {
+		return ""
+	}
+	rest, ok := strings.CutPrefix(u.Path, "/documents/")
+	if !ok {
+		return ""
+	}
+	ref, _, _ := strings.Cut(rest, "/")
+	return ref
+}
+
+// Replaces the links of the document with the links in its content, and
+// links the documents that referenced its slug or id before it had it.
+func setLinks(ctx context.Context, queries *data.Queries, id int64, slug, content string) error {
+	err := queries.ClearDocumentLinks(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, l := range internalLinks(content) {
+		err = queries.AddDocumentLink(ctx, data.AddDocumentLinkParams{
+			Source: id,
+			Href:   l.href,
+			Ref:    l.ref,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return queries.ResolveDocumentLinks(ctx, data.ResolveDocumentLinksParams{
+		Target: id,
+		Refs:   []string{slug, strconv.FormatInt(id, 10)},
+	})
+}
+
+// Returns the documents that link to the document, excluding the ones in the
+// trash.
+func (d Document) Backlinks() ([]Document, error) {
+	ctx := context.TODO()
+	queries := data.New(d.db)
+	ids, err := queries.GetDocumentBacklinks(ctx, d.id)
+	if err != nil {
+		return nil, err
+	}
+	ds := CreateStore(d.db)
+	docs := make([]Document, 0, len(ids))
+	for _, id := range ids {
+		doc, err := ds.GetById(id)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// Returns the published documents that link to the document.
+func (d Document) PublishedBacklinks() ([]Document, error) {
+	docs, err := d.Backlinks()
+	if err != nil {
+		return nil, err
+	}
+	published := make([]Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc.Published() {
+			published = append(published, doc)
+		}
+	}
+	return published, nil
+}
+
+// Returns the links of the document to other documents.
+func (d Document) OutgoingLinks() ([]Link, error) {
+	ctx := context.TODO()
+	queries := data.New(d.db)
+	rows, err := queries.GetDocumentLinks(ctx, d.id)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]Link, len(rows))
+	for i, row := range rows {
+		links[i] = Link{
+			source: row.Source,
+			href:   row.Href,
+			target: row.Target.Int64,
+			broken: row.Broken,
+		}
+	}
+	return links, nil
+}
+
+// Returns the links of the documents that are broken, ie to documents that
+// were deleted.
+func (ds Store) BrokenLinks() ([]Link, error) {
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	rows, err := queries.GetBrokenDocumentLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	links := make([]Link, len(rows))
+	for i, row := range rows {
+		links[i] = Link{
+			source: row.Source,
+			href:   row.Href,
+			target: row.Target.Int64,
+			broken: row.Broken,
+		}
+	}
+	return links, nil
+}
+
+// Extracts the links of every document again, ie for documents saved before
+// links were tracked.
+func (ds Store) RebuildLinks() error {
+	docs, err := CreateStore(ds.db).GetAll()
+	if err != nil {
+		return err
+	}
+	ctx := context.TODO()
+	tx, err := ds.db.BeginTx(ctx, nil)
+	defer tx.Rollback()
+	if err != nil {
+		return err
+	}
+	queries := data.New(ds.db).WithTx(tx)
+	for _, d := range docs {
+		err = setLinks(ctx, queries, d.id, d.slug, d.content)
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err == nil {
+		log.Printf("Rebuilt the links of %d documents\n", len(docs))
+	}
+	return err
+}
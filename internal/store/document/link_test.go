@@ -0,0 +1,73 @@
+package document
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInternalLinks(t *testing.T) {
+	content := "See [intro](/documents/intro#setup), [by id](/documents/12) and [away](https://example.com/documents/other).\n\n" +
+		"[project](/projects/site) [asset](/asset/a.png) [relative](intro)\n\n" +
+		"{{< document id=\"7\" >}}\n"
+	expected := []internalLink{
+		{href: "/documents/intro#setup", ref: "intro"},
+		{href: "/documents/12", ref: "12"},
+		{href: "/documents/7", ref: "7"},
+	}
+	links := internalLinks(content)
+	if len(links) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, links)
+	}
+	for i := range expected {
+		if links[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, links)
+		}
+	}
+}
+
+func TestBacklinks(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	source, err := ds.Add(ProtoDocument{Title: "Source", Content: "[target](/documents/target)", Created: time.Now(), Status: Published})
+	if err != nil {
+		t.Fatal(err)
+	}
+	links, err := source.OutgoingLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || !links[0].Broken() {
+		t.Fatalf("Expected a broken link before the target exists, got %v", links)
+	}
+	target, err := ds.Add(ProtoDocument{Title: "Target", Created: time.Now(), Status: Published})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backlinks, err := target.Backlinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 1 || backlinks[0].Id() != source.Id() {
+		t.Fatalf("Expected the target to be linked from the source, got %v", backlinks)
+	}
+	err = target.Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken, err := ds.BrokenLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(broken) != 1 || broken[0].Source() != source.Id() || broken[0].Target() != target.Id() {
+		t.Fatalf("Expected the link to the deleted target to be broken, got %v", broken)
+	}
+	err = source.Update(func(pd *ProtoDocument) {
+		pd.Content = "No links"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if links, _ := source.OutgoingLinks(); len(links) != 0 {
+		t.Fatalf("Expected the links to be removed with the content, got %v", links)
+	}
+}
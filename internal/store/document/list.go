@@ -0,0 +1,157 @@
+package document
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"time"
+
+	"samuellando.com/data"
+	"samuellando.com/internal/store"
+)
+
+// The order of listed documents.
+type Sort string
+
+const (
+	Newest Sort = "newest"
+	Oldest Sort = "oldest"
+	// Alphabetical, ignoring case.
+	ByTitle Sort = "title"
+)
+
+var Sorts = []Sort{Newest, Oldest, ByTitle}
+
+// Returns the sort with the name, ie "newest".
+func ParseSort(s string) (Sort, error) {
+	for _, sort := range Sorts {
+		if string(sort) == s {
+			return sort, nil
+		}
+	}
+	return "", fmt.Errorf("Invalid sort '%s'", s)
+}
+
+const (
+	// The number of documents on a page when the limit is not set.
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Selects the page of documents to list, see Store.List.
+//
+// The zero value is the first page of every document, newest first.
+type ListOptions struct {
+	// Only the documents with any of the tags, or every document if empty.
+	Tags []string
+	// Only the documents that are published, see Document.Published.
+	Published bool
+	// Only the documents with the status, or every status if empty.
+	Status Status
+	// Only the documents created at or after From, and before To, if they are
+	// not zero.
+	From time.Time
+	To   time.Time
+	Sort Sort
+	// The number of documents on the page, DefaultLimit if 0.
+	Limit int
+	// The number of documents before the page.
+	Offset int
+}
+
+// Returns the options of the query parameters, ie
+// "?filter-tag=go&sort=oldest&from=2024-01-01&offset=20". Invalid parameters
+// are ignored.
+func ParseListOptions(query url.Values) ListOptions {
+	o := ListOptions{Tags: query["filter-tag"]}
+	if status, err := ParseStatus(query.Get("status")); err == nil {
+		o.Status = status
+	}
+	if from, err := time.ParseInLocation("2006-01-02", query.Get("from"), time.Local); err == nil {
+		o.From = from
+	}
+	// The to date is included.
+	if to, err := time.ParseInLocation("2006-01-02", query.Get("to"), time.Local); err == nil {
+		o.To = to.AddDate(0, 0, 1)
+	}
+	if sort, err := ParseSort(query.Get("sort")); err == nil {
+		o.Sort = sort
+	}
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil {
+		o.Limit = limit
+	}
+	// The database pages with 32 bit integers.
+	if offset, err := strconv.Atoi(query.Get("offset")); err == nil {
+		o.Offset = min(offset, math.MaxInt32)
+	}
+	return o
+}
+
+// Returns a page of the documents that match the options, the documents are
+// filtered, sorted and paginated by the database.
+//
+// The page of a filtered store is from all the documents, not only the
+// filtered ones. A page past the last one is empty, and has a total of 0.
+func (ds Store) List(o ListOptions) (store.Page[Document], error) {
+	if o.Sort == "" {
+		o.Sort = Newest
+	}
+	if o.Limit <= 0 {
+		o.Limit = DefaultLimit
+	}
+	o.Limit = min(o.Limit, MaxLimit)
+	o.Offset = min(max(o.Offset, 0), math.MaxInt32)
+	if o.Tags == nil {
+		o.Tags = []string{}
+	}
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	rows, err := queries.ListDocuments(ctx, data.ListDocumentsParams{
+		PublishedOnly: o.Published,
+		Status:        sql.NullString{String: string(o.Status), Valid: o.Status != ""},
+		Tags:          o.Tags,
+		CreatedFrom:   store.NullTime(o.From),
+		CreatedTo:     store.NullTime(o.To),
+		Sort:          string(o.Sort),
+		PageLimit:     int32(o.Limit),
+		PageOffset:    int32(o.Offset),
+	})
+	if err != nil {
+		return store.Page[Document]{}, err
+	}
+	page := store.Page[Document]{
+		Items:  make([]Document, 0, len(rows)),
+		Offset: o.Offset,
+		Limit:  o.Limit,
+	}
+	if len(rows) == 0 {
+		return page, nil
+	}
+	page.Total = int(rows[0].Total)
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	docRows, err := queries.GetDocumentsByIds(ctx, ids)
+	if err != nil {
+		return store.Page[Document]{}, err
+	}
+	converted := make([]data.GetDocumentsRow, len(docRows))
+	for i, row := range docRows {
+		converted[i] = data.GetDocumentsRow(row)
+	}
+	docs := make(map[int64]Document)
+	for _, doc := range documentsFromRows(ds.db, converted) {
+		docs[doc.id] = doc
+	}
+	// In the order of the page.
+	for _, id := range ids {
+		if doc, ok := docs[id]; ok {
+			page.Items = append(page.Items, doc)
+		}
+	}
+	return page, nil
+}
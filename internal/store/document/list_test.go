@@ -0,0 +1,68 @@
+package document
+
+import (
+	"math"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"samuellando.com/internal/store/tag"
+)
+
+func TestParseListOptions(t *testing.T) {
+	query, _ := url.ParseQuery("filter-tag=go&filter-tag=web&status=draft&sort=title&from=2024-01-01&to=2024-01-31&limit=5&offset=10")
+	o := ParseListOptions(query)
+	if !slices.Equal(o.Tags, []string{"go", "web"}) || o.Status != Draft || o.Sort != ByTitle {
+		t.Fatalf("Expected the tags, status and sort of the query, got %+v", o)
+	}
+	if !o.From.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)) || !o.To.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("Expected January 2024 including the 31st, got %s to %s", o.From, o.To)
+	}
+	if o.Limit != 5 || o.Offset != 10 {
+		t.Fatalf("Expected a limit of 5 and an offset of 10, got %d and %d", o.Limit, o.Offset)
+	}
+	query, _ = url.ParseQuery("status=unknown&sort=random&from=yesterday&offset=x")
+	if o := ParseListOptions(query); o.Status != "" || o.Sort != "" || !o.From.IsZero() || o.Offset != 0 {
+		t.Fatalf("Expected invalid parameters to be ignored, got %+v", o)
+	}
+	query, _ = url.ParseQuery("offset=4294967295")
+	if o := ParseListOptions(query); o.Offset != math.MaxInt32 {
+		t.Fatalf("Expected the offset to be at most %d, got %d", math.MaxInt32, o.Offset)
+	}
+}
+
+func TestList(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, title := range []string{"C", "A", "B"} {
+		_, err := ds.Add(ProtoDocument{
+			Title:   title,
+			Created: created.AddDate(0, 0, i),
+			Status:  Published,
+			Tags:    []tag.ProtoTag{{Value: "list"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := ds.Add(ProtoDocument{Title: "Draft", Created: created, Status: Draft})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := ds.List(ListOptions{Published: true, Sort: ByTitle, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || len(page.Items) != 2 || page.Items[0].Title() != "A" || !page.HasNext() {
+		t.Fatalf("Expected the first 2 of 3 published documents by title, got %d of %d", len(page.Items), page.Total)
+	}
+	page, err = ds.List(ListOptions{Tags: []string{"list"}, From: created.AddDate(0, 0, 1), Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || len(page.Items) != 1 || page.Items[0].Title() != "A" || page.HasNext() {
+		t.Fatalf("Expected the second of the 2 newest documents with the tag, got %d of %d", len(page.Items), page.Total)
+	}
+}
@@ -0,0 +1,41 @@
+package document
+
+import (
+	"samuellando.com/internal/related"
+)
+
+func (d Document) relatedItem() related.Item {
+	return related.Item{
+		Kind:  "document",
+		Id:    d.Id(),
+		Title: d.Title(),
+		Url:   d.Url(),
+		Date:  d.Created(),
+		Tags:  tagValues(d.Tags()),
+		Text:  d.ToString(),
+	}
+}
+
+// Returns the n published documents and projects most related to the
+// document, see related.Rank.
+func (d Document) Related(n int) ([]related.Item, error) {
+	return related.Find(d.relatedItem(), n)
+}
+
+// Returns the published documents, as items that can be recommended, for use
+// as a related.Sources.
+func (ds Store) RelatedItems() ([]related.Item, error) {
+	published, err := ds.Published()
+	if err != nil {
+		return nil, err
+	}
+	docs, err := published.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]related.Item, len(docs))
+	for i, d := range docs {
+		items[i] = d.relatedItem()
+	}
+	return items, nil
+}
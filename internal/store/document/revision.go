@@ -0,0 +1,121 @@
+package document
+
+import (
+	"context"
+	"time"
+
+	"samuellando.com/data"
+	"samuellando.com/internal/diff"
+	"samuellando.com/internal/store/tag"
+)
+
+// A saved state of a document, a revision is added every time the document
+// is created or updated.
+type Revision struct {
+	id       int64
+	document int64
+	title    string
+	content  string
+	tags     []string
+	created  time.Time
+}
+
+func (r Revision) Id() int64 {
+	return r.id
+}
+
+// The id of the document the revision belongs to.
+func (r Revision) Document() int64 {
+	return r.document
+}
+
+func (r Revision) Title() string {
+	return r.title
+}
+
+func (r Revision) Content() string {
+	return r.content
+}
+
+// The values of the document's tags.
+func (r Revision) Tags() []string {
+	tags := make([]string, len(r.tags))
+	copy(tags, r.tags)
+	return tags
+}
+
+func (r Revision) Created() time.Time {
+	return r.created
+}
+
+// The lines changed from the other revision to this one.
+func (r Revision) Diff(from Revision) []diff.Line {
+	return diff.Lines(from.Content(), r.Content())
+}
+
+// Returns the revisions of the document, the newest first.
+func (d Document) Revisions() ([]Revision, error) {
+	ctx := context.TODO()
+	queries := data.New(d.db)
+	rows, err := queries.GetDocumentRevisions(ctx, d.id)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]Revision, len(rows))
+	for i, row := range rows {
+		revisions[i] = revisionFromRow(row)
+	}
+	return revisions, nil
+}
+
+// Returns the revision of the document with the id.
+func (d Document) Revision(id int64) (Revision, error) {
+	ctx := context.TODO()
+	queries := data.New(d.db)
+	row, err := queries.GetDocumentRevision(ctx, data.GetDocumentRevisionParams{
+		ID:       id,
+		Document: d.id,
+	})
+	if err != nil {
+		return Revision{}, err
+	}
+	return revisionFromRow(row), nil
+}
+
+// Sets the title, content and tags of the document back to the revision's.
+//
+// The restored state is added as a new revision, so restoring can be undone.
+func (d *Document) RestoreRevision(id int64) error {
+	r, err := d.Revision(id)
+	if err != nil {
+		return err
+	}
+	return d.Update(func(p *ProtoDocument) {
+		p.Title = r.Title()
+		p.Content = r.Content()
+		p.Tags = make([]tag.ProtoTag, len(r.tags))
+		for i, value := range r.tags {
+			p.Tags[i] = tag.ProtoTag{Value: value}
+		}
+	})
+}
+
+func addRevision(ctx context.Context, queries *data.Queries, id int64, p ProtoDocument) error {
+	return queries.CreateDocumentRevision(ctx, data.CreateDocumentRevisionParams{
+		Document: id,
+		Title:    p.Title,
+		Content:  p.Content,
+		Tags:     tagValues(p.Tags),
+	})
+}
+
+func revisionFromRow(row data.DocumentRevision) Revision {
+	return Revision{
+		id:       row.ID,
+		document: row.Document,
+		title:    row.Title,
+		content:  row.Content,
+		tags:     row.Tags,
+		created:  row.Created,
+	}
+}
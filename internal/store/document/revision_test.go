@@ -0,0 +1,115 @@
+package document
+
+import (
+	"testing"
+	"time"
+
+	"samuellando.com/internal/store/tag"
+)
+
+func TestRevisions(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	doc, err := ds.Add(ProtoDocument{
+		Title:   "Sample",
+		Content: "Content",
+		Created: time.Now(),
+		Tags:    []tag.ProtoTag{{Value: "one"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = doc.Update(func(pd *ProtoDocument) {
+		pd.Content = "Content2"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the metadata changes, so there is no new revision.
+	err = doc.Update(func(pd *ProtoDocument) {
+		pd.Summary = "Summary"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := doc.Revisions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("Expected 2 revisions, got %d", len(revisions))
+	}
+	if revisions[0].Content() != "Content2" || revisions[1].Content() != "Content" {
+		t.Fatalf("Expected the newest revision first, got '%s' and '%s'", revisions[0].Content(), revisions[1].Content())
+	}
+	if len(revisions[1].Tags()) != 1 || revisions[1].Tags()[0] != "one" {
+		t.Fatalf("Expected the revision tags to be [one], got %v", revisions[1].Tags())
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	doc, err := ds.Add(ProtoDocument{
+		Title:   "Sample",
+		Content: "Content",
+		Created: time.Now(),
+		Tags:    []tag.ProtoTag{{Value: "one"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = doc.Update(func(pd *ProtoDocument) {
+		pd.Title = "Sample2"
+		pd.Content = ""
+		pd.Tags = nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := doc.Revisions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = doc.RestoreRevision(revisions[len(revisions)-1].Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title() != "Sample" || doc.Content() != "Content" {
+		t.Fatalf("Expected the first revision to be restored, got '%s' and '%s'", doc.Title(), doc.Content())
+	}
+	if len(doc.Tags()) != 1 || doc.Tags()[0].Value != "one" {
+		t.Fatalf("Expected the tags to be restored, got %v", doc.Tags())
+	}
+	revisions, err = doc.Revisions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("Expected the restore to add a revision, got %d revisions", len(revisions))
+	}
+	diff := revisions[0].Diff(revisions[1])
+	if len(diff) != 1 || diff[0].Op != "insert" || diff[0].Text != "Content" {
+		t.Fatalf("Expected the diff to insert 'Content', got %v", diff)
+	}
+}
+
+func TestRestoreRevisionOfOtherDocument(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	doc1, err := ds.Add(ProtoDocument{Title: "One", Content: "One", Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc2, err := ds.Add(ProtoDocument{Title: "Two", Content: "Two", Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := doc1.Revisions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc2.RestoreRevision(revisions[0].Id()); err == nil {
+		t.Fatal("Expected restoring another document's revision to fail")
+	}
+}
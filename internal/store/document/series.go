@@ -0,0 +1,273 @@
+package document
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"samuellando.com/data"
+)
+
+// An ordered collection of documents, ie the parts of a tutorial.
+//
+// A document is in at most one series.
+type Series struct {
+	db          *sql.DB
+	id          int64
+	title       string
+	description string
+	created     time.Time
+	documents   []Document
+}
+
+// A prototype series used for creating and updating series.
+type ProtoSeries struct {
+	Title       string
+	Description string
+}
+
+func (s Series) Id() int64 {
+	return s.id
+}
+
+func (s Series) Title() string {
+	return s.title
+}
+
+func (s Series) Description() string {
+	return s.description
+}
+
+func (s Series) Created() time.Time {
+	return s.created
+}
+
+// The documents of the series, in order.
+func (s Series) Documents() []Document {
+	docs := make([]Document, len(s.documents))
+	copy(docs, s.documents)
+	return docs
+}
+
+// The number of documents in the series.
+func (s Series) Len() int {
+	return len(s.documents)
+}
+
+// The position of the document in the series starting at 1, or 0 if it is
+// not in the series.
+func (s Series) Part(d Document) int {
+	for i, doc := range s.documents {
+		if doc.id == d.id {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// The document before the document in the series, or nil.
+func (s Series) Previous(d Document) *Document {
+	part := s.Part(d)
+	if part < 2 {
+		return nil
+	}
+	return &s.documents[part-2]
+}
+
+// The document after the document in the series, or nil.
+func (s Series) Next(d Document) *Document {
+	part := s.Part(d)
+	if part == 0 || part == len(s.documents) {
+		return nil
+	}
+	return &s.documents[part]
+}
+
+func (s *Series) Update(setters ...func(*ProtoSeries)) error {
+	p := ProtoSeries{
+		Title:       s.title,
+		Description: s.description,
+	}
+	for _, setter := range setters {
+		setter(&p)
+	}
+	ctx := context.TODO()
+	queries := data.New(s.db)
+	err := queries.UpdateSeries(ctx, data.UpdateSeriesParams{
+		ID:          s.id,
+		Title:       p.Title,
+		Description: p.Description,
+	})
+	if err != nil {
+		return err
+	}
+	s.title = p.Title
+	s.description = p.Description
+	return nil
+}
+
+// Sets the documents of the series to the documents with the ids, in order.
+// Documents that are in another series are moved to this one.
+//
+// Documents of the series that are in the trash are kept at their position,
+// so that they are back in the series when they are restored.
+func (s *Series) SetDocuments(ids []int64) error {
+	ctx := context.TODO()
+	tx, err := s.db.BeginTx(ctx, nil)
+	defer tx.Rollback()
+	if err != nil {
+		return err
+	}
+	queries := data.New(s.db).WithTx(tx)
+	err = queries.RemoveSeriesDocumentsExcept(ctx, data.RemoveSeriesDocumentsExceptParams{
+		Series:    s.id,
+		Documents: ids,
+	})
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		err = queries.AddSeriesDocument(ctx, data.AddSeriesDocumentParams{
+			Series:   s.id,
+			Document: id,
+			Position: int32(i + 1),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	docs, err := loadSeriesDocuments(s.db, s.id)
+	if err != nil {
+		return err
+	}
+	s.documents = docs
+	return nil
+}
+
+// Deletes the series, its documents are kept.
+func (s Series) Delete() error {
+	ctx := context.TODO()
+	queries := data.New(s.db)
+	return queries.DeleteSeries(ctx, s.id)
+}
+
+// Returns the series the document is in, or nil.
+//
+// The series only has the documents readers see alongside this one: the
+// published ones and the document itself, so that Part, Previous and Next
+// skip the parts that are not published yet.
+func (d Document) Series() (*Series, error) {
+	ctx := context.TODO()
+	queries := data.New(d.db)
+	row, err := queries.GetDocumentSeries(ctx, d.id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s, err := seriesFromRow(d.db, row.Series)
+	if err != nil {
+		return nil, err
+	}
+	s.documents = slices.DeleteFunc(s.documents, func(doc Document) bool {
+		return doc.id != d.id && !doc.Published()
+	})
+	return &s, nil
+}
+
+// The previous document of the document's series, or nil.
+func (d Document) Previous() (*Document, error) {
+	s, err := d.Series()
+	if s == nil || err != nil {
+		return nil, err
+	}
+	return s.Previous(d), nil
+}
+
+// The next document of the document's series, or nil.
+func (d Document) Next() (*Document, error) {
+	s, err := d.Series()
+	if s == nil || err != nil {
+		return nil, err
+	}
+	return s.Next(d), nil
+}
+
+func (ds Store) AddSeries(p ProtoSeries) (Series, error) {
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	row, err := queries.CreateSeries(ctx, data.CreateSeriesParams{
+		Title:       p.Title,
+		Description: p.Description,
+	})
+	if err != nil {
+		return Series{}, err
+	}
+	return seriesFromRow(ds.db, row)
+}
+
+func (ds Store) GetSeries(id int64) (Series, error) {
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	row, err := queries.GetSeries(ctx, id)
+	if err != nil {
+		return Series{}, err
+	}
+	return seriesFromRow(ds.db, row)
+}
+
+// Returns every series, ordered by title.
+func (ds Store) AllSeries() ([]Series, error) {
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	rows, err := queries.GetAllSeries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	series := make([]Series, len(rows))
+	for i, row := range rows {
+		series[i], err = seriesFromRow(ds.db, row)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return series, nil
+}
+
+func seriesFromRow(db *sql.DB, row data.Series) (Series, error) {
+	docs, err := loadSeriesDocuments(db, row.ID)
+	if err != nil {
+		return Series{}, err
+	}
+	return Series{
+		db:          db,
+		id:          row.ID,
+		title:       row.Title,
+		description: row.Description,
+		created:     row.Created,
+		documents:   docs,
+	}, nil
+}
+
+// Returns the documents of the series in order, excluding the ones in the
+// trash.
+func loadSeriesDocuments(db *sql.DB, id int64) ([]Document, error) {
+	ctx := context.TODO()
+	queries := data.New(db)
+	rows, err := queries.GetSeriesDocuments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	converted := make([]data.GetDocumentsRow, len(rows))
+	for i, row := range rows {
+		converted[i] = data.GetDocumentsRow(row)
+	}
+	return documentsFromRows(db, converted), nil
+}
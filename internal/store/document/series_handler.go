@@ -0,0 +1,161 @@
+package document
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+type SeriesHandler struct {
+	DocumentStore Store
+}
+
+func (h *SeriesHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "POST":
+		h.createSeries(w, req)
+	case "PUT":
+		if req.FormValue("add") != "" || req.FormValue("remove") != "" || req.FormValue("move") != "" {
+			h.updateSeriesDocuments(w, req)
+		} else {
+			h.updateSeries(w, req)
+		}
+	case "DELETE":
+		h.deleteSeries(w, req)
+	}
+}
+
+func (h *SeriesHandler) getReqSeries(w http.ResponseWriter, req *http.Request) (Series, bool) {
+	id, err := strconv.ParseInt(req.PathValue("series"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), err), 400)
+		return Series{}, false
+	}
+	s, err := h.DocumentStore.GetSeries(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(404), "Series not found"), 404)
+		return Series{}, false
+	}
+	return s, true
+}
+
+func (h *SeriesHandler) createSeries(w http.ResponseWriter, req *http.Request) {
+	title := req.PostFormValue("title")
+	if title == "" {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), "Series need a title"), 400)
+		return
+	}
+	_, err := h.DocumentStore.AddSeries(ProtoSeries{
+		Title:       title,
+		Description: req.PostFormValue("description"),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
+
+func (h *SeriesHandler) updateSeries(w http.ResponseWriter, req *http.Request) {
+	s, ok := h.getReqSeries(w, req)
+	if !ok {
+		return
+	}
+	title := req.PostFormValue("title")
+	if title == "" {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), "Series need a title"), 400)
+		return
+	}
+	err := s.Update(func(p *ProtoSeries) {
+		p.Title = title
+		p.Description = req.PostFormValue("description")
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
+
+// Adds, removes or moves a document of the series, ie ?move=3&by=-1 moves
+// the document 3 one part earlier.
+func (h *SeriesHandler) updateSeriesDocuments(w http.ResponseWriter, req *http.Request) {
+	s, ok := h.getReqSeries(w, req)
+	if !ok {
+		return
+	}
+	ids := make([]int64, s.Len())
+	for i, doc := range s.Documents() {
+		ids[i] = doc.Id()
+	}
+	ids, err := reorderSeries(ids, req.FormValue("add"), req.FormValue("remove"), req.FormValue("move"), req.FormValue("by"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), err), 400)
+		return
+	}
+	err = s.SetDocuments(ids)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
+
+// Returns the ids with the document added at the end, removed, or moved by
+// the offset.
+func reorderSeries(ids []int64, add, remove, move, by string) ([]int64, error) {
+	parse := func(s string) (int64, error) {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid document id '%s'", s)
+		}
+		return id, nil
+	}
+	switch {
+	case add != "":
+		id, err := parse(add)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	case remove != "":
+		id, err := parse(remove)
+		if err != nil {
+			return nil, err
+		}
+		ids = slices.DeleteFunc(ids, func(i int64) bool { return i == id })
+	case move != "":
+		id, err := parse(move)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := strconv.Atoi(by)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid offset '%s'", by)
+		}
+		from := slices.Index(ids, id)
+		if from < 0 {
+			return nil, fmt.Errorf("Document %d is not in the series", id)
+		}
+		to := min(max(from+offset, 0), len(ids)-1)
+		ids = slices.Delete(ids, from, from+1)
+		ids = slices.Insert(ids, to, id)
+	}
+	return ids, nil
+}
+
+func (h *SeriesHandler) deleteSeries(w http.ResponseWriter, req *http.Request) {
+	s, ok := h.getReqSeries(w, req)
+	if !ok {
+		return
+	}
+	err := s.Delete()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
@@ -0,0 +1,131 @@
+package document
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSeriesNavigation(t *testing.T) {
+	s := Series{documents: []Document{{id: 1}, {id: 2}, {id: 3}}}
+	if s.Len() != 3 || s.Part(Document{id: 2}) != 2 || s.Part(Document{id: 4}) != 0 {
+		t.Fatalf("Expected document 2 to be part 2 of 3, got %d of %d", s.Part(Document{id: 2}), s.Len())
+	}
+	if s.Previous(Document{id: 1}) != nil || s.Previous(Document{id: 2}).Id() != 1 {
+		t.Fatal("Expected the first document to have no previous document")
+	}
+	if s.Next(Document{id: 3}) != nil || s.Next(Document{id: 2}).Id() != 3 {
+		t.Fatal("Expected the last document to have no next document")
+	}
+	if s.Next(Document{id: 4}) != nil || s.Previous(Document{id: 4}) != nil {
+		t.Fatal("Expected documents not in the series to have no neighbours")
+	}
+}
+
+func TestReorderSeries(t *testing.T) {
+	cases := []struct {
+		add, remove, move, by string
+		expected              []int64
+	}{
+		{add: "4", expected: []int64{1, 2, 3, 4}},
+		{add: "2", expected: []int64{1, 2, 3}},
+		{remove: "2", expected: []int64{1, 3}},
+		{move: "3", by: "-1", expected: []int64{1, 3, 2}},
+		{move: "1", by: "1", expected: []int64{2, 1, 3}},
+		{move: "1", by: "-1", expected: []int64{1, 2, 3}},
+		{move: "2", by: "10", expected: []int64{1, 3, 2}},
+	}
+	for _, c := range cases {
+		ids, err := reorderSeries([]int64{1, 2, 3}, c.add, c.remove, c.move, c.by)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(ids, c.expected) {
+			t.Fatalf("Expected %v for %+v, got %v", c.expected, c, ids)
+		}
+	}
+	if _, err := reorderSeries([]int64{1}, "", "", "5", "1"); err == nil {
+		t.Fatal("Expected an error when moving a document not in the series")
+	}
+}
+
+func TestSeries(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	docs := make([]Document, 3)
+	for i, status := range []Status{Published, Draft, Published} {
+		doc, err := ds.Add(ProtoDocument{Title: "Part", Created: time.Now(), Status: status})
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs[i] = doc
+	}
+	s, err := ds.AddSeries(ProtoSeries{Title: "Tutorial", Description: "In three parts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.SetDocuments([]int64{docs[0].Id(), docs[1].Id(), docs[2].Id()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 3 {
+		t.Fatalf("Expected 3 documents, got %d", s.Len())
+	}
+	series, err := docs[2].Series()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if series == nil || series.Title() != "Tutorial" {
+		t.Fatalf("Expected the document to be in the series, got %v", series)
+	}
+	// The draft is skipped.
+	if series.Part(docs[2]) != 2 || series.Len() != 2 {
+		t.Fatalf("Expected part 2 of 2, got %d of %d", series.Part(docs[2]), series.Len())
+	}
+	previous, err := docs[2].Previous()
+	if err != nil || previous == nil || previous.Id() != docs[0].Id() {
+		t.Fatalf("Expected the first document to be previous, got %v, error: %v", previous, err)
+	}
+	series, err = docs[1].Series()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if series.Part(docs[1]) != 2 || series.Len() != 3 {
+		t.Fatalf("Expected a draft to see itself as part 2 of 3, got %d of %d", series.Part(docs[1]), series.Len())
+	}
+	// Reordering the series keeps the documents in the trash.
+	err = docs[1].Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = ds.GetSeries(s.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 2 {
+		t.Fatalf("Expected the trashed document to be hidden, got %d documents", s.Len())
+	}
+	err = s.SetDocuments([]int64{docs[2].Id(), docs[0].Id()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ds.Restore(docs[1].Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = ds.GetSeries(s.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 3 || s.Part(docs[2]) != 1 {
+		t.Fatalf("Expected the restored document back in the reordered series, got %d documents", s.Len())
+	}
+	err = s.Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	series, err = docs[0].Series()
+	if err != nil || series != nil {
+		t.Fatalf("Expected no series after deleting it, got %v, error: %v", series, err)
+	}
+}
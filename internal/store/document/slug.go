@@ -0,0 +1,122 @@
+package document
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"samuellando.com/data"
+	"samuellando.com/internal/markdown"
+	"samuellando.com/internal/store/tag"
+)
+
+// Returns the document with the slug, or that had the slug before it was
+// changed, in which case its Slug is different.
+//
+// A filtered store only returns its own documents, by their slug or old slugs.
+func (ds Store) GetBySlug(slug string) (Document, error) {
+	if ds.materialized != nil {
+		docs, err := ds.materialized.GetAll()
+		if err != nil {
+			return Document{}, err
+		}
+		for _, d := range docs {
+			if d.slug == slug {
+				return d, nil
+			}
+		}
+		// The old slugs are only known by the database.
+		d, err := CreateStore(ds.db).GetBySlug(slug)
+		if err != nil {
+			return Document{}, err
+		}
+		d, err = ds.materialized.GetById(d.id)
+		if err != nil {
+			return Document{}, fmt.Errorf("Document not found")
+		}
+		return d, nil
+	}
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	rows, err := queries.GetDocumentBySlug(ctx, slug)
+	if err != nil {
+		return Document{}, err
+	}
+	if len(rows) == 0 {
+		return Document{}, fmt.Errorf("Document not found")
+	}
+	d := documentFromRow(ds.db, rows[0].Document)
+	for _, row := range rows {
+		if row.TagID.Valid {
+			d.tags = append(d.tags, tag.ProtoTag{
+				Value: row.TagValue.String,
+				Color: row.TagColor.String,
+			})
+		}
+	}
+	return d, nil
+}
+
+// Returns the document with the slug, or the id if the reference is a number.
+func (ds Store) GetByReference(ref string) (Document, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return ds.GetById(id)
+	}
+	return ds.GetBySlug(ref)
+}
+
+// Returns the slug, or one generated from the title if it is empty, as a url
+// fragment ie "hello-world".
+//
+// Slugs are never only digits, so that they can not be confused with ids.
+func normalizeSlug(slug, title string) string {
+	s := markdown.Slugify(slug)
+	if s == "" {
+		s = markdown.Slugify(title)
+	}
+	if s == "" {
+		return "document"
+	}
+	if _, err := strconv.Atoi(s); err == nil {
+		return "document-" + s
+	}
+	return s
+}
+
+// Returns the slug, numbered ie "intro-2" if it is used by another document,
+// or was used by one.
+func uniqueSlug(ctx context.Context, queries *data.Queries, slug string, id int64) (string, error) {
+	unique := slug
+	for n := 2; ; n++ {
+		taken, err := queries.DocumentSlugTaken(ctx, data.DocumentSlugTakenParams{
+			Slug: unique,
+			ID:   id,
+		})
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return unique, nil
+		}
+		unique = fmt.Sprintf("%s-%d", slug, n)
+	}
+}
+
+// Keeps the old slug of the document as an alias of the new one.
+func moveSlug(ctx context.Context, queries *data.Queries, id int64, from, to string) error {
+	if from == to {
+		return nil
+	}
+	// The document might be getting back one of its old slugs.
+	err := queries.DeleteDocumentSlugAlias(ctx, to)
+	if err != nil {
+		return err
+	}
+	if from == "" {
+		return nil
+	}
+	return queries.SetDocumentSlugAlias(ctx, data.SetDocumentSlugAliasParams{
+		Slug:     from,
+		Document: id,
+	})
+}
@@ -0,0 +1,136 @@
+package document
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNormalizeSlug(t *testing.T) {
+	cases := []struct {
+		slug, title, expected string
+	}{
+		{"", "Hello, World!", "hello-world"},
+		{"My Post", "Hello", "my-post"},
+		{"", "2024", "document-2024"},
+		{"", "!!!", "document"},
+		{"intro", "", "intro"},
+	}
+	for _, c := range cases {
+		if got := normalizeSlug(c.slug, c.title); got != c.expected {
+			t.Fatalf("Expected slug '%s' for '%s' and '%s', got '%s'", c.expected, c.slug, c.title, got)
+		}
+	}
+}
+
+func TestGeneratedSlugs(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	doc1, err := ds.Add(ProtoDocument{Title: "Hello World", Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc2, err := ds.Add(ProtoDocument{Title: "Hello, World", Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc1.Slug() != "hello-world" || doc2.Slug() != "hello-world-2" {
+		t.Fatalf("Expected 'hello-world' and 'hello-world-2', got '%s' and '%s'", doc1.Slug(), doc2.Slug())
+	}
+	doc, err := ds.GetBySlug("hello-world-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Id() != doc2.Id() {
+		t.Fatalf("Expected document %d, got %d", doc2.Id(), doc.Id())
+	}
+	doc, err = ds.GetByReference("hello-world")
+	if err != nil || doc.Id() != doc1.Id() {
+		t.Fatalf("Expected document %d by slug, got %d (%v)", doc1.Id(), doc.Id(), err)
+	}
+	doc, err = ds.GetByReference(strconv.FormatInt(doc2.Id(), 10))
+	if err != nil || doc.Id() != doc2.Id() {
+		t.Fatalf("Expected document %d by id, got %d (%v)", doc2.Id(), doc.Id(), err)
+	}
+}
+
+func TestSlugAliases(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	doc, err := ds.Add(ProtoDocument{Title: "Old", Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = doc.Update(func(pd *ProtoDocument) {
+		pd.Slug = "new"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, err := ds.GetBySlug("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Id() != doc.Id() || moved.Slug() != "new" {
+		t.Fatalf("Expected the old slug to find 'new', got '%s'", moved.Slug())
+	}
+	// The old slug still belongs to the document.
+	other, err := ds.Add(ProtoDocument{Title: "Old", Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Slug() != "old-2" {
+		t.Fatalf("Expected 'old-2', got '%s'", other.Slug())
+	}
+	// Until the document gets it back.
+	err = doc.Update(func(pd *ProtoDocument) {
+		pd.Slug = "old"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Slug() != "old" {
+		t.Fatalf("Expected 'old', got '%s'", doc.Slug())
+	}
+	moved, err = ds.GetBySlug("new")
+	if err != nil || moved.Slug() != "old" {
+		t.Fatalf("Expected the slug 'new' to find 'old', got '%s' (%v)", moved.Slug(), err)
+	}
+}
+
+func TestFilteredSlugAliases(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	doc, err := ds.Add(ProtoDocument{Title: "Old", Created: time.Now(), Status: Published})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = doc.Update(func(pd *ProtoDocument) {
+		pd.Slug = "new"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	draft, err := ds.Add(ProtoDocument{Title: "Draft", Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = draft.Update(func(pd *ProtoDocument) {
+		pd.Slug = "hidden"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	published, err := ds.Published()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered := published.(Store)
+	moved, err := filtered.GetBySlug("old")
+	if err != nil || moved.Id() != doc.Id() {
+		t.Fatalf("Expected the old slug to find the document in the filtered store, got %v", err)
+	}
+	if _, err := filtered.GetBySlug("draft"); err == nil {
+		t.Fatal("Expected the old slug of a document not in the store to not be found")
+	}
+}
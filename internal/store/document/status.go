@@ -0,0 +1,154 @@
+package document
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"time"
+
+	"samuellando.com/data"
+	"samuellando.com/internal/store"
+)
+
+// The publication status of a document.
+type Status string
+
+const (
+	// Only visible to admins, and with the preview link.
+	Draft Status = "draft"
+	// Visible to everyone.
+	Published Status = "published"
+	// Published at the document's PublishAt time.
+	Scheduled Status = "scheduled"
+	// No longer listed, but kept, only visible to admins.
+	Archived Status = "archived"
+)
+
+var Statuses = []Status{Draft, Published, Scheduled, Archived}
+
+// Returns the status with the name, ie "draft".
+func ParseStatus(s string) (Status, error) {
+	for _, status := range Statuses {
+		if string(status) == s {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("Invalid status '%s'", s)
+}
+
+func (d Document) Status() Status {
+	return d.status
+}
+
+// The time a scheduled document is published, or the zero time.
+func (d Document) PublishAt() time.Time {
+	return d.publishAt
+}
+
+// Reports if the document is visible to everyone, scheduled documents are
+// published as soon as their time has passed.
+func (d Document) Published() bool {
+	switch d.status {
+	case Published:
+		return true
+	case Scheduled:
+		return !d.publishAt.IsZero() && !d.publishAt.After(time.Now())
+	}
+	return false
+}
+
+// The token that allows anyone to see the document before it is published.
+func (d Document) PreviewToken() string {
+	return d.previewToken
+}
+
+// A link to the document that works before it is published.
+func (d Document) PreviewLink() string {
+	return fmt.Sprintf("%s?preview=%s", d.Url(), d.previewToken)
+}
+
+// Returns the published documents, see Document.Published.
+func (ds Store) Published() (store.Store[Document], error) {
+	return ds.Filter(func(d Document) bool {
+		return d.Published()
+	})
+}
+
+// Returns the documents an admin, or everyone else, can see.
+func (ds Store) Visible(admin bool) (store.Store[Document], error) {
+	if admin {
+		return ds, nil
+	}
+	return ds.Published()
+}
+
+// Returns the document with the slug or id if it is published, or if the
+// token is its preview token.
+func (ds Store) GetPublishedByReference(ref string, previewToken string) (Document, error) {
+	d, err := ds.GetByReference(ref)
+	if err != nil {
+		return Document{}, err
+	}
+	if d.Published() {
+		return d, nil
+	}
+	if previewToken != "" && subtle.ConstantTimeCompare([]byte(previewToken), []byte(d.previewToken)) == 1 {
+		return d, nil
+	}
+	return Document{}, fmt.Errorf("Document not found")
+}
+
+// Sets the status of scheduled documents whose time has passed to Published,
+// and returns their ids.
+func (ds Store) PublishScheduled() ([]int64, error) {
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	return queries.PublishScheduledDocuments(ctx)
+}
+
+// Publishes the scheduled documents every interval, until the context is
+// done.
+func (ds Store) RunPublisher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ids, err := ds.PublishScheduled()
+		if err != nil {
+			log.Println(err)
+		}
+		for _, id := range ids {
+			log.Printf("Published scheduled document %d\n", id)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Defaults the status of new documents to Draft, and checks that scheduled
+// documents have a time.
+func checkStatus(p *ProtoDocument) error {
+	if p.Status == "" {
+		p.Status = Draft
+	}
+	if _, err := ParseStatus(string(p.Status)); err != nil {
+		return err
+	}
+	if p.Status == Scheduled && p.PublishAt.IsZero() {
+		return fmt.Errorf("Scheduled documents need a publish time")
+	}
+	return nil
+}
+
+func newPreviewToken() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,125 @@
+package document
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStatus(t *testing.T) {
+	for _, status := range Statuses {
+		parsed, err := ParseStatus(string(status))
+		if err != nil || parsed != status {
+			t.Fatalf("Expected %s, got %s (%v)", status, parsed, err)
+		}
+	}
+	if _, err := ParseStatus("public"); err == nil {
+		t.Fatal("Expected an invalid status error")
+	}
+}
+
+func TestPublished(t *testing.T) {
+	cases := []struct {
+		doc      Document
+		expected bool
+	}{
+		{Document{status: Draft}, false},
+		{Document{status: Published}, true},
+		{Document{status: Archived}, false},
+		{Document{status: Scheduled}, false},
+		{Document{status: Scheduled, publishAt: time.Now().Add(time.Hour)}, false},
+		{Document{status: Scheduled, publishAt: time.Now().Add(-time.Hour)}, true},
+	}
+	for _, c := range cases {
+		if c.doc.Published() != c.expected {
+			t.Fatalf("Expected %s document published at %v to be published: %t", c.doc.status, c.doc.publishAt, c.expected)
+		}
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	p := ProtoDocument{}
+	if err := checkStatus(&p); err != nil || p.Status != Draft {
+		t.Fatalf("Expected new documents to be drafts, got %s (%v)", p.Status, err)
+	}
+	p = ProtoDocument{Status: Scheduled}
+	if err := checkStatus(&p); err == nil {
+		t.Fatal("Expected scheduled documents without a time to fail")
+	}
+	p = ProtoDocument{Status: "public"}
+	if err := checkStatus(&p); err == nil {
+		t.Fatal("Expected an invalid status to fail")
+	}
+}
+
+func TestGetPublishedByReference(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	draft, err := ds.Add(ProtoDocument{Title: "Draft", Content: "Draft", Created: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if draft.Status() != Draft {
+		t.Fatalf("Expected new documents to be drafts, got %s", draft.Status())
+	}
+	if _, err := ds.GetPublishedByReference(draft.Slug(), ""); err == nil {
+		t.Fatal("Expected drafts to be hidden")
+	}
+	if _, err := ds.GetPublishedByReference(draft.Slug(), "wrong"); err == nil {
+		t.Fatal("Expected drafts to be hidden with the wrong preview token")
+	}
+	if _, err := ds.GetPublishedByReference(draft.Slug(), draft.PreviewToken()); err != nil {
+		t.Fatalf("Expected the preview token to show the draft, got %s", err)
+	}
+	published, err := ds.Add(ProtoDocument{Title: "Published", Content: "Published", Created: time.Now(), Status: Published})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.GetPublishedByReference(published.Slug(), ""); err != nil {
+		t.Fatalf("Expected published documents to be visible, got %s", err)
+	}
+	visible, err := ds.Visible(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := visible.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].Id() != published.Id() {
+		t.Fatalf("Expected only the published document, got %d documents", len(docs))
+	}
+}
+
+func TestPublishScheduled(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	due, err := ds.Add(ProtoDocument{Title: "Due", Created: time.Now(), Status: Scheduled, PublishAt: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	later, err := ds.Add(ProtoDocument{Title: "Later", Created: time.Now(), Status: Scheduled, PublishAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, err := ds.PublishScheduled()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != due.Id() {
+		t.Fatalf("Expected only document %d to be published, got %v", due.Id(), ids)
+	}
+	due, err = ds.GetById(due.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if due.Status() != Published {
+		t.Fatalf("Expected the due document to be published, got %s", due.Status())
+	}
+	later, err = ds.GetById(later.Id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if later.Status() != Scheduled {
+		t.Fatalf("Expected the later document to still be scheduled, got %s", later.Status())
+	}
+}
@@ -34,23 +34,16 @@ func (ds Store) GetById(id int64) (Document, error) {
 	if len(rows) == 0 {
 		return Document{}, fmt.Errorf("Document not found")
 	}
-	tags := make([]tag.ProtoTag, 0)
+	d := documentFromRow(ds.db, rows[0].Document)
 	for _, row := range rows {
 		if row.TagID.Valid {
-			tags = append(tags, tag.ProtoTag{
+			d.tags = append(d.tags, tag.ProtoTag{
 				Value: row.TagValue.String,
 				Color: row.TagColor.String,
 			})
 		}
 	}
-	return Document{
-		db:      ds.db,
-		id:      rows[0].Document.ID,
-		title:   rows[0].Document.Title,
-		content: rows[0].Document.Content,
-		created: rows[0].Document.Created,
-		tags:    tags,
-	}, nil
+	return d, nil
 }
 
 func (ds Store) GetAll() ([]Document, error) {
@@ -63,17 +56,19 @@ func (ds Store) GetAll() ([]Document, error) {
 	if err != nil {
 		return nil, err
 	}
+	return documentsFromRows(ds.db, docRows), nil
+}
+
+// Returns the documents of the rows, with a row for each of their tags, in the
+// order of the rows.
+func documentsFromRows(db *sql.DB, rows []data.GetDocumentsRow) []Document {
 	docs := make(map[int64]*Document)
-	for _, row := range docRows {
+	order := make([]int64, 0)
+	for _, row := range rows {
 		if _, ok := docs[row.Document.ID]; !ok {
-			docs[row.Document.ID] = &Document{
-				db:      ds.db,
-				id:      row.Document.ID,
-				title:   row.Document.Title,
-				content: row.Document.Content,
-				created: row.Document.Created,
-				tags:    make([]tag.ProtoTag, 0),
-			}
+			doc := documentFromRow(db, row.Document)
+			docs[row.Document.ID] = &doc
+			order = append(order, row.Document.ID)
 		}
 		if row.TagID.Valid {
 			tag := tag.ProtoTag{
@@ -83,11 +78,31 @@ func (ds Store) GetAll() ([]Document, error) {
 			docs[row.Document.ID].tags = append(docs[row.Document.ID].tags, tag)
 		}
 	}
-	res := make([]Document, 0)
-	for _, doc := range docs {
-		res = append(res, *doc)
+	res := make([]Document, len(order))
+	for i, id := range order {
+		res[i] = *docs[id]
+	}
+	return res
+}
+
+// Returns the document of the row, without its tags.
+func documentFromRow(db *sql.DB, row data.Document) Document {
+	return Document{
+		db:           db,
+		id:           row.ID,
+		title:        row.Title,
+		content:      row.Content,
+		created:      row.Created,
+		summary:      row.Summary,
+		slug:         row.Slug,
+		cover:        row.Cover,
+		tags:         make([]tag.ProtoTag, 0),
+		status:       Status(row.Status),
+		publishAt:    row.PublishAt.Time,
+		previewToken: row.PreviewToken,
+		version:      row.Version,
+		deleted:      row.DeletedAt.Time,
 	}
-	return res, nil
 }
 
 func (ds Store) Add(p ProtoDocument) (Document, error) {
@@ -97,11 +112,40 @@ func (ds Store) Add(p ProtoDocument) (Document, error) {
 	if err != nil {
 		return Document{}, err
 	}
-	queries := data.New(ds.db).WithTx(tx)
+	doc, err := ds.add(ctx, data.New(ds.db).WithTx(tx), p)
+	if err != nil {
+		return Document{}, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return Document{}, err
+	}
+	return doc, nil
+}
+
+// Adds the document with the queries of a transaction.
+func (ds Store) add(ctx context.Context, queries *data.Queries, p ProtoDocument) (Document, error) {
+	if err := checkStatus(&p); err != nil {
+		return Document{}, err
+	}
+	previewToken, err := newPreviewToken()
+	if err != nil {
+		return Document{}, err
+	}
+	p.Slug, err = uniqueSlug(ctx, queries, normalizeSlug(p.Slug, p.Title), 0)
+	if err != nil {
+		return Document{}, err
+	}
 	id, err := queries.CreateDocument(ctx, data.CreateDocumentParams{
-		Title:   p.Title,
-		Content: p.Content,
-		Created: p.Created,
+		Title:        p.Title,
+		Content:      p.Content,
+		Created:      p.Created,
+		Summary:      p.Summary,
+		Slug:         p.Slug,
+		Status:       string(p.Status),
+		PublishAt:    store.NullTime(p.PublishAt),
+		Cover:        p.Cover,
+		PreviewToken: previewToken,
 	})
 	if err != nil {
 		return Document{}, err
@@ -113,7 +157,11 @@ func (ds Store) Add(p ProtoDocument) (Document, error) {
 	if err != nil {
 		return Document{}, err
 	}
-	err = tx.Commit()
+	err = addRevision(ctx, queries, id, p)
+	if err != nil {
+		return Document{}, err
+	}
+	err = setLinks(ctx, queries, id, p.Slug, p.Content)
 	if err != nil {
 		return Document{}, err
 	}
@@ -125,12 +173,19 @@ func (ds Store) Add(p ProtoDocument) (Document, error) {
 		}
 	}
 	return Document{
-		db:      ds.db,
-		id:      id,
-		title:   p.Title,
-		content: p.Content,
-		created: p.Created,
-		tags:    tags,
+		db:           ds.db,
+		id:           id,
+		title:        p.Title,
+		content:      p.Content,
+		created:      p.Created,
+		summary:      p.Summary,
+		slug:         p.Slug,
+		cover:        p.Cover,
+		tags:         tags,
+		status:       p.Status,
+		publishAt:    p.PublishAt,
+		previewToken: previewToken,
+		version:      1,
 	}, nil
 }
 
@@ -30,16 +30,20 @@ func setupSampleData(con *sql.DB) {
 	queries := data.New(con).WithTx(tx)
 	id1, err := queries.CreateDocument(ctx, data.CreateDocumentParams{
 		Title:   "First Document",
+		Slug:    "first-document",
 		Content: "Sample",
 		Created: time.Date(2007, 07, 07, 0, 0, 0, 0, &time.Location{}),
+		Status:  "published",
 	})
 	if err != nil {
 		panic(err)
 	}
 	id2, err := queries.CreateDocument(ctx, data.CreateDocumentParams{
 		Title:   "Second Document",
+		Slug:    "second-document",
 		Content: "Sample test 2",
 		Created: time.Now(),
+		Status:  "published",
 	})
 	if err != nil {
 		panic(err)
@@ -0,0 +1,69 @@
+package document
+
+import (
+	"context"
+	"time"
+
+	"samuellando.com/data"
+	apperrors "samuellando.com/internal/errors"
+	"samuellando.com/internal/store"
+)
+
+// The time the document was moved to the trash, or the zero time if it is
+// not in the trash.
+func (d Document) Deleted() time.Time {
+	return d.deleted
+}
+
+// Returns the documents in the trash, the most recently deleted first.
+//
+// They are not part of the store, and are loaded without their tags.
+func (ds Store) Trashed() ([]Document, error) {
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	rows, err := queries.GetDeletedDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]Document, len(rows))
+	for i, row := range rows {
+		docs[i] = documentFromRow(ds.db, row.Document)
+	}
+	return docs, nil
+}
+
+// Moves the document with the id out of the trash.
+func (ds Store) Restore(id int64) error {
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	n, err := queries.RestoreDocument(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apperrors.CreateNotFoundError("Document")
+	}
+	return nil
+}
+
+// Permanently deletes the document with the id, it must be in the trash.
+func (ds Store) Purge(id int64) error {
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	n, err := queries.PurgeDocument(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apperrors.CreateNotFoundError("Document")
+	}
+	return nil
+}
+
+// Permanently deletes the documents moved to the trash before the time, and
+// returns how many were deleted.
+func (ds Store) PurgeDeletedBefore(t time.Time) (int64, error) {
+	ctx := context.TODO()
+	queries := data.New(ds.db)
+	return queries.PurgeDocumentsDeletedBefore(ctx, store.NullTime(t))
+}
@@ -0,0 +1,78 @@
+package document
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	apperrors "samuellando.com/internal/errors"
+)
+
+func TestRestore(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	setupSampleData(con)
+	doc, err := ds.GetById(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = doc.Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	trashed, err := ds.Trashed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 1 || trashed[0].Id() != 1 || trashed[0].Deleted().IsZero() {
+		t.Fatalf("Expected the document to be in the trash, got %+v", trashed)
+	}
+	if _, err := ds.GetBySlug(doc.Slug()); err == nil {
+		t.Fatal("Expected trashed documents to be hidden")
+	}
+	err = ds.Restore(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = ds.GetById(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Deleted().IsZero() {
+		t.Fatalf("Expected the document to be restored, got deleted %s", doc.Deleted())
+	}
+	err = ds.Restore(1)
+	if !errors.As(err, new(apperrors.NotFoundError)) {
+		t.Fatalf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	ds, con := setup()
+	defer teardown(con)
+	setupSampleData(con)
+	err := ds.Purge(1)
+	if !errors.As(err, new(apperrors.NotFoundError)) {
+		t.Fatalf("Expected documents not in the trash to not be purged, got %v", err)
+	}
+	doc, err := ds.GetById(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = doc.Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := ds.PurgeDeletedBefore(time.Now().Add(-time.Hour))
+	if err != nil || n != 0 {
+		t.Fatalf("Expected recently deleted documents to be kept, got %d, error: %v", n, err)
+	}
+	n, err = ds.PurgeDeletedBefore(time.Now().Add(time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 document to be purged, got %d, error: %v", n, err)
+	}
+	err = ds.Restore(1)
+	if err == nil {
+		t.Fatal("Expected purged documents to not be restored")
+	}
+}
@@ -0,0 +1,51 @@
+package store
+
+// A page of the items of a store, and where it is in all of them.
+//
+// The offsets of the pages are the cursors to request them with, ie
+// "?offset=20" for the page after the first 20 items.
+type Page[T Indexable] struct {
+	Items []T
+	// The number of items on all the pages.
+	Total int
+	// The position of the first item of the page in all the items.
+	Offset int
+	// The maximum number of items on a page.
+	Limit int
+}
+
+// Reports if there are items after the page.
+func (p Page[T]) HasNext() bool {
+	return p.Offset+len(p.Items) < p.Total
+}
+
+// The offset of the page after this one.
+func (p Page[T]) Next() int {
+	return p.Offset + p.Limit
+}
+
+// Reports if there are items before the page.
+func (p Page[T]) HasPrevious() bool {
+	return p.Offset > 0
+}
+
+// The offset of the page before this one.
+func (p Page[T]) Previous() int {
+	return max(p.Offset-p.Limit, 0)
+}
+
+// The number of the page starting at 1.
+func (p Page[T]) Number() int {
+	if p.Limit <= 0 {
+		return 1
+	}
+	return p.Offset/p.Limit + 1
+}
+
+// The number of pages, at least 1.
+func (p Page[T]) Pages() int {
+	if p.Limit <= 0 || p.Total == 0 {
+		return 1
+	}
+	return (p.Total + p.Limit - 1) / p.Limit
+}
@@ -0,0 +1,25 @@
+package store
+
+import "testing"
+
+func TestPage(t *testing.T) {
+	items := []elem{new("Monday"), new("Tuesday")}
+	p := Page[elem]{Items: items, Total: 5, Offset: 2, Limit: 2}
+	if !p.HasNext() || p.Next() != 4 {
+		t.Errorf("Expected a next page at 4, got %d", p.Next())
+	}
+	if !p.HasPrevious() || p.Previous() != 0 {
+		t.Errorf("Expected a previous page at 0, got %d", p.Previous())
+	}
+	if p.Number() != 2 || p.Pages() != 3 {
+		t.Errorf("Expected page 2 of 3, got %d of %d", p.Number(), p.Pages())
+	}
+	last := Page[elem]{Items: items[:1], Total: 5, Offset: 4, Limit: 2}
+	if last.HasNext() || last.Number() != 3 {
+		t.Errorf("Expected page 3 to be the last, got %d", last.Number())
+	}
+	empty := Page[elem]{Limit: 2}
+	if empty.HasNext() || empty.HasPrevious() || empty.Pages() != 1 {
+		t.Error("Expected an empty page to be the only page")
+	}
+}
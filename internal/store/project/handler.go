@@ -1,6 +1,7 @@
 package project
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,6 +9,8 @@ import (
 	"strings"
 
 	"html/template"
+	apperrors "samuellando.com/internal/errors"
+	"samuellando.com/internal/etag"
 	"samuellando.com/internal/store/tag"
 )
 
@@ -32,12 +35,41 @@ func (h *Handler) templateRequest(w http.ResponseWriter, req *http.Request) {
 }
 
 func (h *Handler) renderProject(w http.ResponseWriter, project Project) {
+	if project.Id() != 0 {
+		etag.Set(w, project.Version())
+	}
 	err := h.Template.Execute(w, project)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
 	}
 }
 
+// Checks that the request is based on the current version of the project,
+// otherwise responds with an error and returns false.
+func (h *Handler) checkVersion(w http.ResponseWriter, req *http.Request, project Project) bool {
+	match, err := etag.Match(req, project.Version())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(428), err), 428)
+		return false
+	}
+	if !match {
+		h.conflict(w, req)
+	}
+	return match
+}
+
+// Responds with the current version of the project, when the request was
+// based on an older one.
+func (h *Handler) conflict(w http.ResponseWriter, req *http.Request) {
+	project := h.getReqProject(req)
+	etag.Set(w, project.Version())
+	w.WriteHeader(http.StatusConflict)
+	err := h.Template.Execute(w, project)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
 func (h *Handler) getReqProject(req *http.Request) Project {
 	id, err := strconv.Atoi(req.PathValue("project"))
 	if err != nil {
@@ -64,6 +96,13 @@ func (h *Handler) getTagsFromReq(req *http.Request) []tag.ProtoTag {
 
 func (h *Handler) updateProject(w http.ResponseWriter, req *http.Request) {
 	proj := h.getReqProject(req)
+	if proj.Id() == 0 {
+		http.NotFound(w, req)
+		return
+	}
+	if !h.checkVersion(w, req, proj) {
+		return
+	}
 	rdesc := req.PostFormValue("description")
 	rimage := req.PostFormValue("image")
 	rhidden := req.PostFormValue("hidden")
@@ -86,6 +125,10 @@ func (h *Handler) updateProject(w http.ResponseWriter, req *http.Request) {
 		pf.Tags = tags
 		pf.Hidden = hidden
 	})
+	if errors.As(err, new(apperrors.ConflictError)) {
+		h.conflict(w, req)
+		return
+	}
 	if err != nil {
 		log.Println(err)
 		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
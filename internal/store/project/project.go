@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"samuellando.com/data"
+	"samuellando.com/internal/errors"
 	"samuellando.com/internal/store/tag"
 	"strings"
 	"time"
@@ -21,6 +22,7 @@ type Project struct {
 	imageLink   *string
 	hidden      bool
 	tags        []tag.ProtoTag
+	version     int64
 }
 
 type ProtoProject struct {
@@ -69,6 +71,14 @@ func (p Project) Tags() []tag.ProtoTag {
 	return copyOf(p.tags)
 }
 
+// The number of times the project was updated, 0 until its first update.
+func (p Project) Version() int64 {
+	return p.version
+}
+
+// Update a project
+//
+// Returns a ConflictError if the project was updated since it was loaded.
 func (p *Project) Update(setters ...func(*ProtoProject)) error {
 	desc := p.Description()
 	proto := ProtoProject{
@@ -96,15 +106,19 @@ func (p *Project) Update(setters ...func(*ProtoProject)) error {
 	if proto.ImageLink != nil {
 		sqlimage = sql.NullString{Valid: true, String: *proto.ImageLink}
 	}
-	err = queries.UpdateProject(ctx, data.UpdateProjectParams{
+	updated, err := queries.UpdateProject(ctx, data.UpdateProjectParams{
 		ID:          p.id,
 		Description: sqldesc,
 		ImageLink:   sqlimage,
 		Hidden:      proto.Hidden,
+		Version:     p.version,
 	})
 	if err != nil {
 		return err
 	}
+	if updated == 0 {
+		return errors.CreateConflictError("Project")
+	}
 	tagRows, err := queries.SetProjectTags(ctx, data.SetProjectTagsParams{
 		Project:   p.id,
 		TagValues: tagValues(proto.Tags),
@@ -124,7 +138,10 @@ func (p *Project) Update(setters ...func(*ProtoProject)) error {
 		}
 	}
 	p.description = proto.Description
+	p.imageLink = proto.ImageLink
+	p.hidden = proto.Hidden
 	p.tags = tags
+	p.version++
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package project
 
 import (
+	"samuellando.com/internal/errors"
 	"samuellando.com/internal/store/tag"
 	"testing"
 	"time"
@@ -124,3 +125,35 @@ func TestTagConflicts(t *testing.T) {
 		t.Fatal("The tag is missing!")
 	}
 }
+
+func TestUpdateConflict(t *testing.T) {
+	ps, ts, db := setup()
+	defer teardown(ts, db)
+
+	proj1, _ := ps.GetById(1)
+	proj2, _ := ps.GetById(1)
+	if proj1.Version() != 0 {
+		t.Fatalf("Expected a project that was never updated to have version 0, got %d", proj1.Version())
+	}
+	desc := "First"
+	err := proj1.Update(func(pp *ProtoProject) {
+		pp.Description = &desc
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proj1.Version() != 1 {
+		t.Fatalf("Expected version 1, got %d", proj1.Version())
+	}
+	desc2 := "Second"
+	err = proj2.Update(func(pp *ProtoProject) {
+		pp.Description = &desc2
+	})
+	if _, ok := err.(errors.ConflictError); !ok {
+		t.Fatalf("Expected a conflict error, got %v", err)
+	}
+	proj, _ := ps.GetById(1)
+	if proj.Description() != "First" {
+		t.Fatalf("Expected the first update to be kept, got '%s'", proj.Description())
+	}
+}
@@ -0,0 +1,39 @@
+package project
+
+import (
+	"samuellando.com/internal/related"
+)
+
+func (p Project) relatedItem() related.Item {
+	return related.Item{
+		Kind:  "project",
+		Id:    p.Id(),
+		Title: p.Title(),
+		Url:   p.Url(),
+		Date:  p.Pushed(),
+		Tags:  tagValues(p.Tags()),
+		Text:  p.ToString(),
+	}
+}
+
+// Returns the n published documents and projects most related to the
+// project, see related.Rank.
+func (p Project) Related(n int) ([]related.Item, error) {
+	return related.Find(p.relatedItem(), n)
+}
+
+// Returns the projects that are not hidden, as items that can be recommended,
+// for use as a related.Sources.
+func (ps Store) RelatedItems() ([]related.Item, error) {
+	projects, err := ps.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]related.Item, 0, len(projects))
+	for _, p := range projects {
+		if !p.Hidden() {
+			items = append(items, p.relatedItem())
+		}
+	}
+	return items, nil
+}
@@ -264,6 +264,7 @@ func getInternalProjectData(ctx context.Context, queries *data.Queries, id int64
 		imageLink:   imageLink,
 		hidden:      rows[0].Project.Hidden,
 		tags:        tags,
+		version:     rows[0].Project.Version,
 	}, nil
 }
 
@@ -289,6 +290,7 @@ func getAllInternalProjectData(ctx context.Context, queries *data.Queries) (map[
 				imageLink:   imageLink,
 				hidden:      row.Project.Hidden,
 				tags:        make([]tag.ProtoTag, 0),
+				version:     row.Project.Version,
 			}
 		}
 		if row.TagID.Valid {
@@ -321,5 +323,6 @@ func coallesceProjectData(internal, external Project) Project {
 		url:         external.url,
 		imageLink:   internal.imageLink,
 		hidden:      internal.hidden,
+		version:     internal.version,
 	}
 }
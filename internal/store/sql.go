@@ -0,0 +1,11 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Returns the time as a nullable column, null if it is the zero time.
+func NullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
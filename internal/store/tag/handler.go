@@ -1,9 +1,12 @@
 package tag
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+
+	apperrors "samuellando.com/internal/errors"
 )
 
 type Handler struct {
@@ -52,6 +55,14 @@ func (h *Handler) deleteTag(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 	err = asset.Delete()
+	if errors.Is(err, ErrInUse) {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(409), err), 409)
+		return
+	}
+	if errors.As(err, new(apperrors.NotFoundError)) {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(404), err), 404)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Faild to delete tag", 500)
 		return
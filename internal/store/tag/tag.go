@@ -3,15 +3,23 @@ package tag
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
+
 	"samuellando.com/data"
+	apperrors "samuellando.com/internal/errors"
 )
 
+// Returned when deleting a tag that documents or projects still have.
+var ErrInUse = errors.New("Tag is still in use")
+
 type Tag struct {
-	db    *sql.DB
-	id    int64
-	value string
-	color string
+	db      *sql.DB
+	id      int64
+	value   string
+	color   string
+	deleted time.Time
 }
 
 type ProtoTag struct {
@@ -57,9 +65,28 @@ func (a *Tag) Update(opts ...func(*ProtoTag)) error {
 	return nil
 }
 
+// Moves the tag to the trash, see Store.Restore and Store.Purge. Tags that
+// are in use can not be deleted.
+//
+// Returns ErrInUse if the tag is in use, and a NotFoundError if it no longer
+// exists or is already in the trash.
 func (a *Tag) Delete() error {
 	ctx := context.TODO()
 	queries := data.New(a.db)
-	err := queries.DeleteTag(ctx, a.Id())
-	return err
+	n, err := queries.TrashTag(ctx, a.Id())
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// Either the tag is in use, or there is no tag to trash.
+		_, err = queries.GetTag(ctx, a.Id())
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperrors.CreateNotFoundError("Tag")
+		}
+		if err != nil {
+			return err
+		}
+		return ErrInUse
+	}
+	return nil
 }
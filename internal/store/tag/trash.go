@@ -0,0 +1,73 @@
+package tag
+
+import (
+	"context"
+	"time"
+
+	"samuellando.com/data"
+	apperrors "samuellando.com/internal/errors"
+	"samuellando.com/internal/store"
+)
+
+// The time the tag was moved to the trash, or the zero time if it is not in
+// the trash.
+func (a Tag) Deleted() time.Time {
+	return a.deleted
+}
+
+// Returns the tags in the trash, the most recently deleted first.
+func (as Store) Trashed() ([]Tag, error) {
+	ctx := context.TODO()
+	queries := data.New(as.db)
+	rows, err := queries.GetDeletedTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]Tag, len(rows))
+	for i, row := range rows {
+		tags[i] = Tag{
+			db:      as.db,
+			id:      row.Tag.ID,
+			value:   row.Tag.Value,
+			color:   row.Tag.Color,
+			deleted: row.Tag.DeletedAt.Time,
+		}
+	}
+	return tags, nil
+}
+
+// Moves the tag with the id out of the trash.
+func (as Store) Restore(id int64) error {
+	ctx := context.TODO()
+	queries := data.New(as.db)
+	n, err := queries.RestoreTag(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apperrors.CreateNotFoundError("Tag")
+	}
+	return nil
+}
+
+// Permanently deletes the tag with the id, it must be in the trash.
+func (as Store) Purge(id int64) error {
+	ctx := context.TODO()
+	queries := data.New(as.db)
+	n, err := queries.PurgeTag(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apperrors.CreateNotFoundError("Tag")
+	}
+	return nil
+}
+
+// Permanently deletes the tags moved to the trash before the time, and
+// returns how many were deleted.
+func (as Store) PurgeDeletedBefore(t time.Time) (int64, error) {
+	ctx := context.TODO()
+	queries := data.New(as.db)
+	return queries.PurgeTagsDeletedBefore(ctx, store.NullTime(t))
+}
@@ -0,0 +1,66 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	apperrors "samuellando.com/internal/errors"
+)
+
+type Handler struct {
+	Trash Trash
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "POST":
+		h.restoreItem(w, req)
+	case "DELETE":
+		h.purgeItem(w, req)
+	}
+}
+
+func (h *Handler) restoreItem(w http.ResponseWriter, req *http.Request) {
+	kind, id, ok := h.getReqItem(w, req)
+	if !ok {
+		return
+	}
+	h.writeResult(w, h.Trash.Restore(kind, id))
+}
+
+func (h *Handler) purgeItem(w http.ResponseWriter, req *http.Request) {
+	kind, id, ok := h.getReqItem(w, req)
+	if !ok {
+		return
+	}
+	h.writeResult(w, h.Trash.Purge(kind, id))
+}
+
+// Returns the kind and id of the item in the path, or writes an error.
+func (h *Handler) getReqItem(w http.ResponseWriter, req *http.Request) (string, int64, bool) {
+	kind := req.PathValue("kind")
+	if kind != DocumentKind && kind != AssetKind && kind != TagKind {
+		http.Error(w, fmt.Sprintf("%s : Invalid kind '%s'", http.StatusText(404), kind), 404)
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(400), err), 400)
+		return "", 0, false
+	}
+	return kind, id, true
+}
+
+func (h *Handler) writeResult(w http.ResponseWriter, err error) {
+	if errors.As(err, new(apperrors.NotFoundError)) {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(404), err), 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
@@ -0,0 +1,138 @@
+// This package brings together the documents, assets and tags that were
+// deleted, so they can be restored or purged from one place.
+package trash
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"samuellando.com/internal/store/asset"
+	"samuellando.com/internal/store/document"
+	"samuellando.com/internal/store/tag"
+)
+
+// The kinds of items that can be in the trash.
+const (
+	DocumentKind = "document"
+	AssetKind    = "asset"
+	TagKind      = "tag"
+)
+
+// An item in the trash.
+type Item struct {
+	Kind    string
+	Id      int64
+	Name    string
+	Deleted time.Time
+}
+
+type Trash struct {
+	Documents document.Store
+	Assets    asset.Store
+	Tags      tag.Store
+}
+
+// Returns the items in the trash, the most recently deleted first.
+func (t Trash) Items() ([]Item, error) {
+	items := make([]Item, 0)
+	docs, err := t.Documents.Trashed()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range docs {
+		items = append(items, Item{Kind: DocumentKind, Id: d.Id(), Name: d.Title(), Deleted: d.Deleted()})
+	}
+	assets, err := t.Assets.Trashed()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assets {
+		items = append(items, Item{Kind: AssetKind, Id: a.Id(), Name: a.Name(), Deleted: a.Deleted()})
+	}
+	tags, err := t.Tags.Trashed()
+	if err != nil {
+		return nil, err
+	}
+	for _, tg := range tags {
+		items = append(items, Item{Kind: TagKind, Id: tg.Id(), Name: tg.Value(), Deleted: tg.Deleted()})
+	}
+	sortItems(items)
+	return items, nil
+}
+
+// Moves the item out of the trash.
+func (t Trash) Restore(kind string, id int64) error {
+	switch kind {
+	case DocumentKind:
+		return t.Documents.Restore(id)
+	case AssetKind:
+		return t.Assets.Restore(id)
+	case TagKind:
+		return t.Tags.Restore(id)
+	}
+	return fmt.Errorf("Invalid kind '%s'", kind)
+}
+
+// Permanently deletes the item, it must be in the trash.
+func (t Trash) Purge(kind string, id int64) error {
+	switch kind {
+	case DocumentKind:
+		return t.Documents.Purge(id)
+	case AssetKind:
+		return t.Assets.Purge(id)
+	case TagKind:
+		return t.Tags.Purge(id)
+	}
+	return fmt.Errorf("Invalid kind '%s'", kind)
+}
+
+// Permanently deletes the items moved to the trash before the time, and
+// returns how many were deleted.
+//
+// Documents are purged before tags, a tag can only be deleted once the
+// documents that have it are gone.
+func (t Trash) PurgeDeletedBefore(before time.Time) (int64, error) {
+	purged := int64(0)
+	for _, purge := range []func(time.Time) (int64, error){
+		t.Documents.PurgeDeletedBefore,
+		t.Assets.PurgeDeletedBefore,
+		t.Tags.PurgeDeletedBefore,
+	} {
+		n, err := purge(before)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// Purges the items that have been in the trash for longer than the retention
+// every interval, until the context is done.
+func (t Trash) RunRetention(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := t.PurgeDeletedBefore(time.Now().Add(-retention))
+		if err != nil {
+			log.Println(err)
+		}
+		if n > 0 {
+			log.Printf("Purged %d items from the trash\n", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sortItems(items []Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Deleted.After(items[j].Deleted)
+	})
+}
@@ -0,0 +1,45 @@
+package trash
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSortItems(t *testing.T) {
+	now := time.Now()
+	items := []Item{
+		{Kind: TagKind, Id: 1, Deleted: now.Add(-time.Hour)},
+		{Kind: DocumentKind, Id: 2, Deleted: now},
+		{Kind: AssetKind, Id: 3, Deleted: now.Add(-2 * time.Hour)},
+	}
+	sortItems(items)
+	for i, id := range []int64{2, 1, 3} {
+		if items[i].Id != id {
+			t.Fatalf("Expected item %d to be %d, got %d", i, id, items[i].Id)
+		}
+	}
+}
+
+func TestHandlerInvalidItem(t *testing.T) {
+	h := &Handler{}
+	mux := http.NewServeMux()
+	mux.Handle("/trash/{kind}/{id}", h)
+	cases := []struct {
+		method, path string
+		expected     int
+	}{
+		{"POST", "/trash/project/1", 404},
+		{"DELETE", "/trash/user/1", 404},
+		{"POST", "/trash/document/abc", 400},
+		{"DELETE", "/trash/tag/1.5", 400},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+		if w.Code != c.expected {
+			t.Fatalf("Expected %s %s to be %d, got %d", c.method, c.path, c.expected, w.Code)
+		}
+	}
+}
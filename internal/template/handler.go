@@ -38,6 +38,8 @@ type ContextValues map[string]ContextValue
 type Handler struct {
 	Templates     Template
 	ContextValues map[string]ContextValue
+	// Returns the url the page has permanently moved to, or an empty string.
+	Redirect func(Context) string
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -47,18 +49,25 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 			req := ctx.Get("Req").(*http.Request)
 			return req.URL.Path
 		},
+		// The part of the path matched by a [slug] directory, or an empty
+		// string.
+		"Slug": func(ctx Context) any {
+			_, slug := h.resolveTemplate(path.Join("pages", ctx.Get("Page").(string)))
+			return slug
+		},
 	})
 	h.renderTemplate(ctxt, w, req)
 }
 
 func (h *Handler) renderTemplate(ctxt Context, w http.ResponseWriter, req *http.Request) {
-	template := path.Join("pages", ctxt.Get("Page").(string))
-	// Check that the template exists
-	if h.Templates.Lookup(template) == nil {
-		// Check for a slug
-		template = filepath.Dir(template) + "/[slug]"
-		if h.Templates.Lookup(template) == nil {
-			http.NotFound(w, req)
+	template, _ := h.resolveTemplate(path.Join("pages", ctxt.Get("Page").(string)))
+	if template == "" {
+		http.NotFound(w, req)
+		return
+	}
+	if h.Redirect != nil {
+		if url := h.Redirect(ctxt); url != "" {
+			http.Redirect(w, req, url, http.StatusMovedPermanently)
 			return
 		}
 	}
@@ -68,3 +77,23 @@ func (h *Handler) renderTemplate(ctxt Context, w http.ResponseWriter, req *http.
 		http.Error(w, fmt.Sprintf("%s : %s", http.StatusText(500), err), 500)
 	}
 }
+
+// Returns the name of the page template, or an empty string if there is none,
+// and the part of the path matched by its [slug] directory.
+//
+// A [slug] directory matches any last part of the path, or the part before
+// it, so "pages/a/1/b" can be served by "pages/a/[slug]/b".
+func (h *Handler) resolveTemplate(template string) (string, string) {
+	dir := filepath.Dir(template)
+	candidates := [][2]string{
+		{template, ""},
+		{dir + "/[slug]", filepath.Base(template)},
+		{filepath.Dir(dir) + "/[slug]/" + filepath.Base(template), filepath.Base(dir)},
+	}
+	for _, c := range candidates {
+		if h.Templates.Lookup(c[0]) != nil {
+			return c[0], c[1]
+		}
+	}
+	return "", ""
+}
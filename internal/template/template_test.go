@@ -1,6 +1,7 @@
 package template
 
 import (
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -233,3 +234,53 @@ func TestLoadTamplateCollision(t *testing.T) {
 		t.Fatal("Collisons should throw errors")
 	}
 }
+
+// Test that pages are resolved from [slug] directories
+func TestResolveTemplate(t *testing.T) {
+	files := fstest.MapFS{
+		"pages/docs/+page.html":                  {Data: []byte("docs")},
+		"pages/docs/[slug]/+page.html":           {Data: []byte("doc")},
+		"pages/docs/[slug]/revisions/+page.html": {Data: []byte("revisions")},
+	}
+	h := Handler{Templates: *New("test").ParseFs(files)}
+	cases := map[string][2]string{
+		"pages/docs":                 {"pages/docs", ""},
+		"pages/docs/1":               {"pages/docs/[slug]", "1"},
+		"pages/docs/intro":           {"pages/docs/[slug]", "intro"},
+		"pages/docs/intro/revisions": {"pages/docs/[slug]/revisions", "intro"},
+		"pages/docs/1/other":         {"", ""},
+		"pages/other":                {"", ""},
+	}
+	for page, expected := range cases {
+		template, slug := h.resolveTemplate(page)
+		if template != expected[0] || slug != expected[1] {
+			t.Fatalf("Expected %s to resolve to '%s' with slug '%s', got '%s' with '%s'", page, expected[0], expected[1], template, slug)
+		}
+	}
+}
+
+// Test that pages are redirected, and that the slug is in the context
+func TestRedirect(t *testing.T) {
+	files := fstest.MapFS{
+		"pages/docs/[slug]/+page.html": {Data: []byte(`{{.Get "Slug"}}`)},
+	}
+	h := Handler{
+		Templates: *New("test").ParseFs(files),
+		Redirect: func(ctx Context) string {
+			if ctx.Get("Slug") == "old" {
+				return "/docs/new"
+			}
+			return ""
+		},
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/docs/old", nil))
+	if w.Code != 301 || w.Header().Get("Location") != "/docs/new" {
+		t.Fatalf("Expected a redirect to /docs/new, got %d %s", w.Code, w.Header().Get("Location"))
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/docs/new", nil))
+	if w.Code != 200 || w.Body.String() != "new" {
+		t.Fatalf("Expected the page with slug 'new', got %d %s", w.Code, w.Body.String())
+	}
+}
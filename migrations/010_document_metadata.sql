@@ -0,0 +1,5 @@
+ALTER TABLE document
+ADD COLUMN summary text NOT NULL DEFAULT '',
+ADD COLUMN slug text NOT NULL DEFAULT '',
+ADD COLUMN draft boolean NOT NULL DEFAULT false,
+ADD COLUMN cover text NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS document_revision (
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    document bigint NOT NULL REFERENCES document (id) ON DELETE CASCADE,
+    title text NOT NULL,
+    content text NOT NULL,
+    tags text[] NOT NULL DEFAULT '{}',
+    created timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS document_revision_document ON document_revision (document);
+-- The current state of existing documents is their first revision.
+INSERT INTO document_revision (document, title, content, tags)
+SELECT d.id, d.title, d.content, COALESCE(array_agg(t.value ORDER BY t.value) FILTER (WHERE t.value IS NOT NULL), '{}')
+FROM document d
+LEFT JOIN document_tag dt ON dt.document = d.id
+LEFT JOIN tag t ON dt.tag = t.id
+GROUP BY d.id;
@@ -0,0 +1,9 @@
+ALTER TABLE document
+ADD COLUMN status text NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'published', 'scheduled', 'archived')),
+ADD COLUMN publish_at timestamp with time zone,
+ADD COLUMN preview_token text NOT NULL DEFAULT '';
+UPDATE document SET status = 'draft' WHERE draft;
+UPDATE document SET preview_token = md5(random()::text || id::text);
+ALTER TABLE document DROP COLUMN draft;
+CREATE INDEX IF NOT EXISTS document_scheduled ON document (publish_at) WHERE status = 'scheduled';
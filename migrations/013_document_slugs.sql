@@ -0,0 +1,19 @@
+-- Generate the missing slugs from the titles, numbering the duplicates.
+UPDATE document
+SET slug = trim(both '-' from lower(regexp_replace(title, '[^[:alnum:]]+', '-', 'g')))
+WHERE slug = '';
+UPDATE document SET slug = 'document' WHERE slug = '';
+UPDATE document SET slug = 'document-' || slug WHERE slug ~ '^[0-9]+$';
+UPDATE document d
+SET slug = d.slug || '-' || d.id
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY slug ORDER BY id) AS n
+    FROM document
+) duplicate
+WHERE duplicate.id = d.id AND duplicate.n > 1;
+CREATE UNIQUE INDEX IF NOT EXISTS document_slug ON document (slug);
+-- The previous slugs of documents, which redirect to their current slug.
+CREATE TABLE IF NOT EXISTS document_slug_alias (
+    slug text PRIMARY KEY,
+    document bigint NOT NULL REFERENCES document (id) ON DELETE CASCADE
+);
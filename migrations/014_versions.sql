@@ -0,0 +1,4 @@
+ALTER TABLE document
+ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE project
+ADD COLUMN version bigint NOT NULL DEFAULT 1;
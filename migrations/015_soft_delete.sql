@@ -0,0 +1,6 @@
+ALTER TABLE document
+ADD COLUMN deleted_at timestamptz;
+ALTER TABLE asset
+ADD COLUMN deleted_at timestamptz;
+ALTER TABLE tag
+ADD COLUMN deleted_at timestamptz;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS series (
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    title text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    created timestamp with time zone NOT NULL DEFAULT now()
+);
+-- The documents of each series, in order. A document is in at most one series.
+CREATE TABLE IF NOT EXISTS series_document (
+    series bigint NOT NULL REFERENCES series (id) ON DELETE CASCADE,
+    document bigint NOT NULL UNIQUE REFERENCES document (id) ON DELETE CASCADE,
+    position integer NOT NULL,
+    PRIMARY KEY (series, document)
+);
@@ -0,0 +1,11 @@
+-- The internal links of each document, to the documents they reference by
+-- slug or id. The target is null when no document has the reference.
+CREATE TABLE IF NOT EXISTS document_link (
+    source bigint NOT NULL REFERENCES document (id) ON DELETE CASCADE,
+    href text NOT NULL,
+    ref text NOT NULL,
+    target bigint REFERENCES document (id) ON DELETE SET NULL,
+    PRIMARY KEY (source, href)
+);
+CREATE INDEX IF NOT EXISTS document_link_target ON document_link (target);
+CREATE INDEX IF NOT EXISTS document_link_ref ON document_link (ref);
@@ -0,0 +1,2 @@
+ALTER TABLE asset
+ADD COLUMN content_hash text GENERATED ALWAYS AS (md5(content)) STORED;
@@ -1,17 +1,18 @@
 -- name: GetAssets :many
 SELECT id, name, created
-FROM asset;
+FROM asset
+WHERE deleted_at IS NULL;
 
 -- name: GetAsset :one
 SELECT sqlc.embed(asset)
 FROM asset
-WHERE id = $1
+WHERE id = $1 AND deleted_at IS NULL
 LIMIT 1;
 
 -- name: GetAssetByName :one
 SELECT sqlc.embed(asset)
 FROM asset
-WHERE name = $1
+WHERE name = $1 AND deleted_at IS NULL
 LIMIT 1;
 
 -- name: GetAssetContent :one
@@ -20,13 +21,37 @@ FROM asset
 WHERE id = $1
 LIMIT 1;
 
+-- name: GetAssetHash :one
+SELECT content_hash
+FROM asset
+WHERE name = $1 AND deleted_at IS NULL
+LIMIT 1;
+
 -- name: CreateAsset :one
 INSERT INTO asset (name, content, created)
 VALUES ($1, $2, DEFAULT)
 ON CONFLICT (name) DO UPDATE
-SET content = $2
+SET content = $2, deleted_at = NULL
 RETURNING sqlc.embed(asset);
 
--- name: DeleteAsset :exec
+-- name: TrashAsset :exec
+UPDATE asset SET deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreAsset :execrows
+UPDATE asset SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeAsset :execrows
+DELETE FROM asset
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeAssetsDeletedBefore :execrows
 DELETE FROM asset
-WHERE id = $1;
+WHERE deleted_at < $1;
+
+-- name: GetDeletedAssets :many
+SELECT id, name, created, deleted_at
+FROM asset
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
@@ -10,3 +10,6 @@ INSERT INTO cache (cache_key, cache_value, valid_to) VALUES
 ON CONFLICT (cache_key) DO UPDATE 
 SET cache_value = EXCLUDED.cache_value,
     valid_to = EXCLUDED.valid_to;
+
+-- name: DeleteCacheByKey :exec
+DELETE FROM cache WHERE cache_key = $1;
@@ -7,7 +7,24 @@ SELECT
 FROM document d
 LEFT JOIN document_tag dt ON dt.document = d.id
 LEFT JOIN tag t ON dt.tag = t.id
-WHERE d.id = $1
+WHERE d.id = $1 AND d.deleted_at IS NULL
+ORDER BY d.id, t.value;
+
+-- name: GetDocumentBySlug :many
+SELECT 
+    sqlc.embed(d),
+    t.id as tag_id,
+    t.value as tag_value,
+    t.color as tag_color
+FROM document d
+LEFT JOIN document_tag dt ON dt.document = d.id
+LEFT JOIN tag t ON dt.tag = t.id
+WHERE d.id = (
+    SELECT document.id FROM document WHERE document.slug = $1
+    UNION ALL
+    SELECT a.document FROM document_slug_alias a WHERE a.slug = $1
+    LIMIT 1
+) AND d.deleted_at IS NULL
 ORDER BY d.id, t.value;
 
 -- name: GetDocuments :many
@@ -19,19 +36,160 @@ SELECT
 FROM document d
 LEFT JOIN document_tag dt ON dt.document = d.id
 LEFT JOIN tag t ON dt.tag = t.id
+WHERE d.deleted_at IS NULL
+ORDER BY d.id, t.value;
+
+-- name: ListDocuments :many
+SELECT d.id, count(*) OVER () AS total
+FROM document d
+WHERE d.deleted_at IS NULL
+    AND (NOT sqlc.arg(published_only)::boolean
+        OR d.status = 'published'
+        OR (d.status = 'scheduled' AND d.publish_at <= now()))
+    AND (sqlc.narg(status)::text IS NULL OR d.status = sqlc.narg(status)::text)
+    AND (cardinality(sqlc.arg(tags)::text[]) = 0 OR EXISTS (
+        SELECT 1 FROM document_tag dt
+        INNER JOIN tag t ON t.id = dt.tag
+        WHERE dt.document = d.id AND t.value = ANY(sqlc.arg(tags)::text[])
+    ))
+    AND (sqlc.narg(created_from)::timestamptz IS NULL OR d.created >= sqlc.narg(created_from)::timestamptz)
+    AND (sqlc.narg(created_to)::timestamptz IS NULL OR d.created < sqlc.narg(created_to)::timestamptz)
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'oldest' THEN d.created END ASC,
+    CASE WHEN sqlc.arg(sort)::text = 'title' THEN lower(d.title) END ASC,
+    CASE WHEN sqlc.arg(sort)::text = 'newest' THEN d.created END DESC,
+    d.id DESC
+LIMIT sqlc.arg(page_limit)::integer OFFSET sqlc.arg(page_offset)::integer;
+
+-- name: GetDocumentsByIds :many
+SELECT
+    sqlc.embed(d),
+    t.id as tag_id,
+    t.value as tag_value,
+    t.color as tag_color
+FROM document d
+LEFT JOIN document_tag dt ON dt.document = d.id
+LEFT JOIN tag t ON dt.tag = t.id
+WHERE d.id = ANY(sqlc.arg(ids)::bigint[])
 ORDER BY d.id, t.value;
 
 -- name: CreateDocument :one
-INSERT INTO document (title, content, created) VALUES ($1, $2, $3)
+INSERT INTO document (title, content, created, summary, slug, status, publish_at, cover, preview_token)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING id;
 
--- name: UpdateDocument :exec
+-- name: UpdateDocument :execrows
 UPDATE document SET 
     title = $1,
     content = $2,
-    created = $3
+    created = $3,
+    summary = $4,
+    slug = $5,
+    status = $6,
+    publish_at = $7,
+    cover = $8,
+    version = version + 1
 WHERE 
-    id = $4;
+    id = $9 AND version = $10;
+
+-- name: PublishScheduledDocuments :many
+UPDATE document SET status = 'published', version = version + 1
+WHERE status = 'scheduled' AND publish_at <= now() AND deleted_at IS NULL
+RETURNING id;
+
+-- name: TrashDocument :exec
+UPDATE document SET deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreDocument :execrows
+UPDATE document SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeDocument :execrows
+DELETE FROM document
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeDocumentsDeletedBefore :execrows
+DELETE FROM document
+WHERE deleted_at < $1;
+
+-- name: GetDeletedDocuments :many
+SELECT sqlc.embed(document)
+FROM document
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
+
+-- name: CreateDocumentRevision :exec
+INSERT INTO document_revision (document, title, content, tags)
+VALUES ($1, $2, $3, sqlc.arg(tags)::text[]);
+
+-- name: GetDocumentRevisions :many
+SELECT * FROM document_revision
+WHERE document = $1
+ORDER BY created DESC, id DESC;
+
+-- name: GetDocumentRevision :one
+SELECT * FROM document_revision
+WHERE id = $1 AND document = $2;
 
--- name: DeleteDocument :exec
-DELETE FROM document WHERE id = $1;
+-- name: DocumentSlugTaken :one
+SELECT EXISTS (
+    SELECT 1 FROM document WHERE slug = $1 AND id <> $2
+    UNION ALL
+    SELECT 1 FROM document_slug_alias WHERE slug = $1 AND document <> $2
+);
+
+-- name: SetDocumentSlugAlias :exec
+INSERT INTO document_slug_alias (slug, document)
+VALUES ($1, $2)
+ON CONFLICT (slug) DO UPDATE SET document = EXCLUDED.document;
+
+-- name: DeleteDocumentSlugAlias :exec
+DELETE FROM document_slug_alias WHERE slug = $1;
+
+-- name: ClearDocumentLinks :exec
+DELETE FROM document_link WHERE source = $1;
+
+-- name: AddDocumentLink :exec
+INSERT INTO document_link (source, href, ref, target)
+VALUES ($1, $2, sqlc.arg(ref)::text, (
+    SELECT document.id FROM document WHERE document.slug = sqlc.arg(ref)::text OR document.id::text = sqlc.arg(ref)::text
+    UNION ALL
+    SELECT a.document FROM document_slug_alias a WHERE a.slug = sqlc.arg(ref)::text
+    LIMIT 1
+))
+ON CONFLICT (source, href) DO NOTHING;
+
+-- name: ResolveDocumentLinks :exec
+UPDATE document_link SET target = sqlc.arg(target)::bigint
+WHERE target IS NULL AND ref = ANY(sqlc.arg(refs)::text[]);
+
+-- name: GetDocumentBacklinks :many
+SELECT DISTINCT l.source
+FROM document_link l
+INNER JOIN document d ON d.id = l.source
+WHERE l.target = sqlc.arg(document)::bigint AND l.source <> sqlc.arg(document)::bigint AND d.deleted_at IS NULL
+ORDER BY l.source;
+
+-- name: GetDocumentLinks :many
+SELECT
+    l.source,
+    l.href,
+    l.target,
+    (t.id IS NULL OR t.deleted_at IS NOT NULL)::boolean AS broken
+FROM document_link l
+LEFT JOIN document t ON t.id = l.target
+WHERE l.source = $1
+ORDER BY l.href;
+
+-- name: GetBrokenDocumentLinks :many
+SELECT
+    l.source,
+    l.href,
+    l.target,
+    true::boolean AS broken
+FROM document_link l
+INNER JOIN document s ON s.id = l.source
+LEFT JOIN document t ON t.id = l.target
+WHERE s.deleted_at IS NULL AND (t.id IS NULL OR t.deleted_at IS NOT NULL)
+ORDER BY l.source, l.href;
@@ -21,10 +21,12 @@ LEFT JOIN project_tag pt ON pt.project = p.id
 LEFT JOIN tag t ON pt.tag = t.id
 ORDER BY p.id, t.value;
 
--- name: UpdateProject :exec
+-- name: UpdateProject :execrows
 INSERT INTO project (id, description, image_link, hidden) 
 VALUES ($1, $2, $3, $4)
 ON CONFLICT (id) DO UPDATE
 SET description = $2,
 image_link = $3,
-hidden = $4;
+hidden = $4,
+version = project.version + 1
+WHERE project.version = sqlc.arg(version);
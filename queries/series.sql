@@ -0,0 +1,56 @@
+-- name: GetAllSeries :many
+SELECT * FROM series
+ORDER BY title, id;
+
+-- name: GetSeries :one
+SELECT * FROM series
+WHERE id = $1;
+
+-- name: GetDocumentSeries :one
+SELECT sqlc.embed(s)
+FROM series s
+INNER JOIN series_document sd ON sd.series = s.id
+WHERE sd.document = $1;
+
+-- name: CreateSeries :one
+INSERT INTO series (title, description)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: UpdateSeries :exec
+UPDATE series SET
+    title = $1,
+    description = $2
+WHERE id = $3;
+
+-- name: DeleteSeries :exec
+DELETE FROM series WHERE id = $1;
+
+-- name: GetSeriesDocuments :many
+SELECT
+    sqlc.embed(d),
+    t.id as tag_id,
+    t.value as tag_value,
+    t.color as tag_color
+FROM series_document sd
+INNER JOIN document d ON d.id = sd.document
+LEFT JOIN document_tag dt ON dt.document = d.id
+LEFT JOIN tag t ON dt.tag = t.id
+WHERE sd.series = $1 AND d.deleted_at IS NULL
+ORDER BY sd.position, sd.document, t.value;
+
+-- Removes the documents of the series that are not in the list, the documents
+-- in the trash are kept.
+-- name: RemoveSeriesDocumentsExcept :exec
+DELETE FROM series_document sd
+USING document d
+WHERE sd.series = sqlc.arg(series)
+    AND d.id = sd.document
+    AND d.deleted_at IS NULL
+    AND sd.document <> ALL(sqlc.arg(documents)::bigint[]);
+
+-- name: AddSeriesDocument :exec
+INSERT INTO series_document (series, document, position)
+VALUES ($1, $2, $3)
+ON CONFLICT (document) DO UPDATE
+SET series = EXCLUDED.series, position = EXCLUDED.position;
@@ -8,7 +8,7 @@ tags AS (
     INSERT INTO tag (value)
     SELECT unnest(sqlc.arg(tag_values)::text[])
     ON CONFLICT (value) DO UPDATE
-    SET value = tag.value
+    SET value = tag.value, deleted_at = NULL
     RETURNING id, value, color
 ),
 document_tags AS (
@@ -27,6 +27,8 @@ ORDER BY value;
 SELECT DISTINCT sqlc.embed(t)
 FROM document_tag dt
 INNER JOIN tag t ON dt.tag = t.id
+INNER JOIN document d ON d.id = dt.document
+WHERE d.deleted_at IS NULL
 ORDER BY value;
 
 -- name: GetSharedDocumentTags :many
@@ -62,7 +64,7 @@ tags AS (
     INSERT INTO tag (value)
     SELECT unnest(sqlc.arg(tag_values)::text[])
     ON CONFLICT (value) DO UPDATE
-    SET value = tag.value
+    SET value = tag.value, deleted_at = NULL
     RETURNING id, value, color
 ),
 project_tags AS (
@@ -112,27 +114,48 @@ ORDER BY value;
 -- name: GetTags :many
 SELECT sqlc.embed(tag)
 FROM tag
+WHERE deleted_at IS NULL
 ORDER BY value;
 
 -- name: GetTag :one
 SELECT sqlc.embed(tag)
 FROM tag
-WHERE id = $1
+WHERE id = $1 AND deleted_at IS NULL
 LIMIT 1;
 
 -- name: GetTagByValue :one
 SELECT sqlc.embed(tag)
 FROM tag
-WHERE value = $1
+WHERE value = $1 AND deleted_at IS NULL
 LIMIT 1;
 
 -- name: CreateOrUpdateTag :one
 INSERT INTO tag (value, color)
 VALUES ($1, $2) 
 ON CONFLICT (value) DO UPDATE
-SET color = $2
+SET color = $2, deleted_at = NULL
 RETURNING sqlc.embed(tag);
 
--- name: DeleteTag :exec
+-- name: TrashTag :execrows
+UPDATE tag SET deleted_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+    AND NOT EXISTS (SELECT 1 FROM document_tag WHERE tag = $1)
+    AND NOT EXISTS (SELECT 1 FROM project_tag WHERE tag = $1);
+
+-- name: RestoreTag :execrows
+UPDATE tag SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeTag :execrows
+DELETE FROM tag
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeTagsDeletedBefore :execrows
 DELETE FROM tag
-WHERE id = $1;
+WHERE deleted_at < $1;
+
+-- name: GetDeletedTags :many
+SELECT sqlc.embed(tag)
+FROM tag
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;